@@ -17,12 +17,51 @@ type WebAPIConfiguration struct {
 		Path string `conf:"default:/conf/config.yml"`
 	}
 	Web struct {
-		APIHost         string        `conf:"default:0.0.0.0:3000"`
-		DebugHost       string        `conf:"default:0.0.0.0:4000"`
+		APIHost string `conf:"default:0.0.0.0:3000"`
+
+		// DebugHost is where the metrics are served (/debug/vars), only locally by default. Empty disables it.
+		DebugHost       string        `conf:"default:localhost:4000"`
 		ReadTimeout     time.Duration `conf:"default:5s"`
 		WriteTimeout    time.Duration `conf:"default:5s"`
 		ShutdownTimeout time.Duration `conf:"default:5s"`
 	}
+	Auth struct {
+		SessionTTL time.Duration `conf:"default:720h"`
+
+		// RequirePassword protects accounts with a password. Existing users without one can't log in until they set it
+		// from a session started before.
+		RequirePassword bool `conf:"default:false"`
+	}
+	Messages struct {
+		// EditWindow is how long after sending a message its sender can edit it
+		EditWindow time.Duration `conf:"default:15m"`
+
+		// DeleteWindow is how long after sending a message its sender can delete it for everyone
+		DeleteWindow time.Duration `conf:"default:48h"`
+	}
+	Media struct {
+		// Backend is where uploaded files are stored: "local" (in Dir) or "s3" (in an S3-compatible bucket)
+		Backend string `conf:"default:local"`
+		Dir     string `conf:"default:uploads"`
+
+		// URLKey signs the URLs of the photos sent in chats, a random key is used if empty. URLTTL is how long a
+		// signed URL stays valid.
+		URLKey string        `conf:"mask"`
+		URLTTL time.Duration `conf:"default:1h"`
+
+		// GCInterval is how often the uploaded files not used anywhere are removed, once they have been unused for
+		// GCGracePeriod
+		GCInterval    time.Duration `conf:"default:1h"`
+		GCGracePeriod time.Duration `conf:"default:24h"`
+
+		S3 struct {
+			Endpoint  string `conf:"flag:media-s3-endpoint,env:MEDIA_S3_ENDPOINT"`
+			Region    string `conf:"default:us-east-1,flag:media-s3-region,env:MEDIA_S3_REGION"`
+			Bucket    string `conf:"flag:media-s3-bucket,env:MEDIA_S3_BUCKET"`
+			AccessKey string `conf:"flag:media-s3-access-key,env:MEDIA_S3_ACCESS_KEY"`
+			SecretKey string `conf:"mask,flag:media-s3-secret-key,env:MEDIA_S3_SECRET_KEY"`
+		}
+	}
 	Debug bool
 	DB    struct {
 		Filename string `conf:"default:/tmp/decaf.db"`
@@ -2,7 +2,8 @@
 Webapi is the executable for the main web server.
 It builds a web server around APIs from `service/api`.
 Webapi connects to external resources needed (database) and starts two web servers: the API web server, and the debug.
-Everything is served via the API web server, except debug variables (/debug/vars) and profiler infos (pprof).
+Everything is served via the API web server, except debug variables (/debug/vars) and profiler infos (pprof). The debug
+server only listens on localhost by default, and is disabled if its host is empty.
 
 Usage:
 
@@ -96,6 +97,16 @@ func run() error {
 		logger.WithError(err).Error("error creating AppDatabase")
 		return fmt.Errorf("creating AppDatabase: %w", err)
 	}
+	if !db.SearchAvailable() {
+		logger.Warn("SQLite was built without FTS5, message search is disabled: build with `-tags sqlite_fts5`")
+	}
+
+	// Init the storage of the uploaded files
+	storage, err := newMediaStorage(cfg)
+	if err != nil {
+		logger.WithError(err).Error("error creating the media storage")
+		return fmt.Errorf("creating the media storage: %w", err)
+	}
 
 	// Start (main) API server
 	logger.Info("initializing API server")
@@ -111,8 +122,17 @@ func run() error {
 
 	// Create the API router
 	apirouter, err := api.New(api.Config{
-		Logger:   logger,
-		Database: db,
+		Logger:              logger,
+		Database:            db,
+		SessionTTL:          cfg.Auth.SessionTTL,
+		RequirePassword:     cfg.Auth.RequirePassword,
+		MessageEditWindow:   cfg.Messages.EditWindow,
+		MessageDeleteWindow: cfg.Messages.DeleteWindow,
+		Media:               storage,
+		MediaURLKey:         []byte(cfg.Media.URLKey),
+		MediaURLTTL:         cfg.Media.URLTTL,
+		MediaGCInterval:     cfg.Media.GCInterval,
+		MediaGCGracePeriod:  cfg.Media.GCGracePeriod,
 	})
 	if err != nil {
 		logger.WithError(err).Error("error creating the API server instance")
@@ -145,6 +165,26 @@ func run() error {
 		logger.Infof("stopping API server")
 	}()
 
+	// Start the debug server, if enabled, which exposes the metrics (e.g., of the media janitor) in /debug/vars
+	if cfg.Web.DebugHost != "" {
+		debugmux := http.NewServeMux()
+		debugmux.HandleFunc("/debug/vars", serveDebugVars)
+		debugserver := http.Server{
+			Addr:              cfg.Web.DebugHost,
+			Handler:           debugmux,
+			ReadHeaderTimeout: cfg.Web.ReadTimeout,
+		}
+		go func() {
+			logger.Infof("debug listening on %s", debugserver.Addr)
+			if err := debugserver.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.WithError(err).Warning("debug server error")
+			}
+		}()
+		defer func() {
+			_ = debugserver.Close()
+		}()
+	}
+
 	// Waiting for shutdown signal or POSIX signals
 	select {
 	case err := <-serverErrors:
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/DavideStummSapienza/WASAText/service/media"
+)
+
+// newMediaStorage creates the storage of the uploaded files selected by the configuration.
+func newMediaStorage(cfg WebAPIConfiguration) (media.Storage, error) {
+	switch cfg.Media.Backend {
+	case "local":
+		return media.NewLocalStorage(cfg.Media.Dir)
+	case "s3":
+		return media.NewS3Storage(media.S3Config{
+			Endpoint:  cfg.Media.S3.Endpoint,
+			Region:    cfg.Media.S3.Region,
+			Bucket:    cfg.Media.S3.Bucket,
+			AccessKey: cfg.Media.S3.AccessKey,
+			SecretKey: cfg.Media.S3.SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown media backend %q", cfg.Media.Backend)
+	}
+}
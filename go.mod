@@ -1,6 +1,6 @@
 module github.com/DavideStummSapienza/WASAText
 
-go 1.17
+go 1.20
 
 require (
 	github.com/ardanlabs/conf v1.5.0
@@ -9,6 +9,8 @@ require (
 	github.com/julienschmidt/httprouter v1.3.0
 	github.com/mattn/go-sqlite3 v1.14.23
 	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/crypto v0.27.0
+	golang.org/x/image v0.18.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -21,7 +22,8 @@ type AddToGroupResponse struct {
 	Message string `json:"message"`
 }
 
-// addToGroup handles adding users to a group (creates group if not existing)
+// addToGroup handles adding users to a group (creates group if not existing, with the current user as owner).
+// Only the owner and the admins can add users to an existing group.
 func (rt *_router) addToGroup(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
 	// Set response content type
@@ -72,11 +74,17 @@ func (rt *_router) addToGroup(w http.ResponseWriter, r *http.Request, _ httprout
 	// Add users to group in database
 	err = rt.db.AddToGroup(req.GroupName, req.Names, username)
 	log.Print(err)
-	if err != nil {
+	if errors.Is(err, database.ErrNotGroupMember) || errors.Is(err, database.ErrNotGroupAdmin) {
+		writeGroupError(w, err, http.StatusForbidden)
+		return
+	} else if err != nil {
 		http.Error(w, `{"error": "failed to add users to group: `+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
 
+	// Notify the group members (including the new ones).
+	rt.notifyConversation(username, req.GroupName, events.Event{Type: events.GroupChanged, Actor: username})
+
 	// Send success response
 	response := AddToGroupResponse{Message: "Users successfully added to the group. The group was created if it did not exist."}
 	w.WriteHeader(http.StatusOK)
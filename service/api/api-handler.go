@@ -17,39 +17,62 @@ func (rt *_router) Handler() http.Handler {
 	rt.router.GET("/liveness", rt.liveness)
 
 	// Image serving route
-	rt.router.ServeFiles("/uploads/*filepath", http.Dir("./uploads")) // make images publicly accessable
+	rt.router.GET("/uploads/:name", rt.serveMedia) // make images publicly accessable
 
 	// Login
 	rt.router.POST("/session", rt.login)
 
 	// | Protected Routes |
+	// Routes about a conversation or a message also check that the user takes part in it, see authorization.go. The
+	// sender of a message can see its receipts even after leaving its conversation.
+
+	// Logout
+	rt.router.DELETE("/session", rt.wrapWithAuth(rt.logout))
 
 	// Image Upload
 	rt.router.POST("/upload", rt.wrapWithAuth(rt.uploadImage))
 
+	// Real-time events
+	rt.router.GET("/events", rt.wrapWithAuth(rt.streamEvents))
+
 	// Search
 	rt.router.GET("/users", rt.wrapWithAuth(rt.searchUsers))
+	rt.router.GET("/users/:username", rt.wrapWithAuth(rt.getUser))
+	rt.router.GET("/search/messages", rt.wrapWithAuth(rt.searchMessages))
 
 	// User Profile
 	rt.router.PUT("/user-profile", rt.wrapWithAuth(rt.changeUsername))
 	rt.router.GET("/user-profile", rt.wrapWithAuth(rt.listConversations))
 	rt.router.PUT("/profile-picture", rt.wrapWithAuth(rt.changeProfilePicture))
+	rt.router.PUT("/password", rt.wrapWithAuth(rt.setPassword))
 
 	// Conversation
-	rt.router.GET("/conversations/:partner-username", rt.wrapWithAuth(rt.showConversation))
-	rt.router.POST("/conversations/:partner-username", rt.wrapWithAuth(rt.sendMessage))
-	rt.router.POST("/conversations/:partner-username/messages/:message-id", rt.wrapWithAuth(rt.forwardMessage))
-	rt.router.DELETE("/conversations/messages/:message-id", rt.wrapWithAuth(rt.deleteMessage))
+	rt.router.GET("/conversations/:partner-username", rt.wrapWithConversationAccess(rt.showConversation))
+	rt.router.POST("/conversations/:partner-username", rt.wrapWithConversationAccess(rt.sendMessage))
+	rt.router.POST("/conversations/:partner-username/messages/:message-id", rt.wrapWithMessageAccess(rt.forwardMessage))
+	rt.router.POST("/conversations/:partner-username/received", rt.wrapWithConversationAccess(rt.markMessagesReceived))
+	rt.router.POST("/conversations/:partner-username/read", rt.wrapWithConversationAccess(rt.markMessagesRead))
+	rt.router.PUT("/conversations/messages/:message-id", rt.wrapWithMessageAccess(rt.editMessage))
+	rt.router.DELETE("/conversations/messages/:message-id", rt.wrapWithMessageAccess(rt.deleteMessage))
+	rt.router.GET("/messages/:message-id/edits", rt.wrapWithMessageAccess(rt.getMessageEdits))
+	rt.router.GET("/messages/:message-id/receipts", rt.wrapWithSenderMessageAccess(rt.getMessageReceipts))
+
+	// Settings of a conversation for the user. They can't be under /conversations/:partner-username, as httprouter
+	// doesn't allow a PUT route there next to /conversations/messages/:message-id.
+	rt.router.PUT("/conversation-settings/:partner-username", rt.wrapWithConversationAccess(rt.setConversationSettings))
 
 	// Comment
-	rt.router.PUT("/conversations/messages/:message-id/comment", rt.wrapWithAuth(rt.makeComment))
-	rt.router.DELETE("/conversations/messages/:message-id/comment", rt.wrapWithAuth(rt.deleteComment))
+	rt.router.PUT("/conversations/messages/:message-id/comment", rt.wrapWithMessageAccess(rt.makeComment))
+	rt.router.DELETE("/conversations/messages/:message-id/comment", rt.wrapWithMessageAccess(rt.deleteComment))
 
 	// Groups
 	rt.router.POST("/groups", rt.wrapWithAuth(rt.addToGroup))
+	rt.router.GET("/groups/:groupname", rt.wrapWithAuth(rt.getGroup))
 	rt.router.PUT("/groups/:groupname", rt.wrapWithAuth(rt.changeGroupName))
 	rt.router.DELETE("/groups/:groupname", rt.wrapWithAuth(rt.leaveGroup))
 	rt.router.PUT("/groups/:groupname/group-photo", rt.wrapWithAuth(rt.changeGroupPicture))
+	rt.router.PUT("/groups/:groupname/members/:username/role", rt.wrapWithAuth(rt.setGroupRole))
+	rt.router.DELETE("/groups/:groupname/members/:username", rt.wrapWithAuth(rt.removeFromGroup))
 
 	return rt.router
 }
@@ -0,0 +1,330 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DavideStummSapienza/WASAText/service/globaltime"
+	"github.com/DavideStummSapienza/WASAText/service/media"
+	"github.com/sirupsen/logrus"
+)
+
+// fakePrivateMedia is a stored file that is only served with a signed URL.
+const fakePrivateMedia = "fedcba9876543210.png"
+
+// newTestServer starts the API on the fake database, with the fake public and private files in the media storage.
+func newTestServer(t *testing.T) (*_router, *httptest.Server) {
+	t.Helper()
+
+	storage, err := media.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{fakePublicMedia, fakePrivateMedia} {
+		if err := storage.Put(context.Background(), name, testPNG(t), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	router, err := New(Config{
+		Logger:              logger,
+		Database:            &fakeDatabase{},
+		SessionTTL:          time.Hour,
+		MessageEditWindow:   time.Hour,
+		MessageDeleteWindow: time.Hour,
+		Media:               storage,
+		MediaURLTTL:         time.Hour,
+		MediaGCInterval:     time.Hour,
+		MediaGCGracePeriod:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(router.Handler())
+	t.Cleanup(func() {
+		server.Close()
+		_ = router.Close()
+	})
+	return router.(*_router), server
+}
+
+// testPNG returns a small PNG image.
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testUpload returns the body and the content type of a form uploading a PNG image as `image`.
+func testUpload(t *testing.T) (string, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreateFormFile("image", "image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := part.Write(testPNG(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String(), form.FormDataContentType()
+}
+
+// do sends a request as `user` (no session if empty) and returns the response status.
+func do(t *testing.T, server *httptest.Server, method, path, user, body, contentType string) int {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user != "" {
+		req.Header.Set("Authorization", "Bearer token-"+user)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The event stream never ends: only its head is read
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+// TestRoutes checks every route of Handler(): what each user can access, and the responses of the access checks.
+// See fakeDatabase for the users, groups and messages.
+func TestRoutes(t *testing.T) {
+	rt, server := newTestServer(t)
+
+	signed := "/uploads/" + fakePrivateMedia + "?" +
+		rt.mediaSigner.Sign(fakePrivateMedia, globaltime.Now().Add(time.Hour))
+	upload, uploadType := testUpload(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		user        string // User sending the request, no session if empty
+		body        string
+		contentType string
+		want        int
+	}{
+		// Unprotected routes
+		{name: "hello world", method: "GET", path: "/", want: 200},
+		{name: "context", method: "GET", path: "/context", want: 200},
+		{name: "liveness", method: "GET", path: "/liveness", want: 200},
+		{name: "public upload", method: "GET", path: "/uploads/" + fakePublicMedia, want: 200},
+		{name: "private upload", method: "GET", path: "/uploads/" + fakePrivateMedia, want: 404},
+		{name: "private upload, signed", method: "GET", path: signed, want: 200},
+		{name: "private upload, bad signature", method: "GET", path: "/uploads/" + fakePrivateMedia + "?expires=1&sig=x", want: 403},
+		{name: "login", method: "POST", path: "/session", body: `{"username": "alice"}`, want: 200},
+		{name: "login, new user", method: "POST", path: "/session", body: `{"username": "dave"}`, want: 200},
+		{name: "login, invalid body", method: "POST", path: "/session", body: `{`, want: 400},
+
+		// Session, uploads and events
+		{name: "logout", method: "DELETE", path: "/session", user: "alice", want: 200},
+		{name: "upload", method: "POST", path: "/upload", user: "alice", body: upload, contentType: uploadType, want: 200},
+		{name: "upload, no file", method: "POST", path: "/upload", user: "alice", want: 400},
+		{name: "events", method: "GET", path: "/events", user: "alice", want: 200},
+
+		// Search
+		{name: "search users", method: "GET", path: "/users?username=bo", user: "alice", want: 200},
+		{name: "search users, empty", method: "GET", path: "/users", user: "alice", want: 400},
+		{name: "get user", method: "GET", path: "/users/bob", user: "alice", want: 200},
+		{name: "get user, unknown", method: "GET", path: "/users/nobody", user: "alice", want: 404},
+		{name: "search messages", method: "GET", path: "/search/messages?q=hi", user: "alice", want: 200},
+		{name: "search messages, empty", method: "GET", path: "/search/messages", user: "alice", want: 400},
+
+		// User profile
+		{name: "change username", method: "PUT", path: "/user-profile", user: "alice", body: `{"newusername": "alicia"}`, want: 200},
+		{name: "change username, taken", method: "PUT", path: "/user-profile", user: "alice", body: `{"newusername": "bob"}`, want: 400},
+		{name: "list conversations", method: "GET", path: "/user-profile", user: "alice", want: 200},
+		{name: "list conversations, archived", method: "GET", path: "/user-profile?include_archived=true", user: "alice", want: 200},
+		{name: "list conversations, invalid flag", method: "GET", path: "/user-profile?include_archived=maybe", user: "alice", want: 400},
+		{name: "change profile picture", method: "PUT", path: "/profile-picture", user: "alice", body: `{"photo_url": "https://example.com/a.png"}`, want: 200},
+		{name: "set password", method: "PUT", path: "/password", user: "alice", body: `{"password": "correct horse"}`, want: 200},
+		{name: "set password, too short", method: "PUT", path: "/password", user: "alice", body: `{"password": "horse"}`, want: 400},
+
+		// Conversations: the partner must exist, and a group must have the user as member
+		{name: "show conversation", method: "GET", path: "/conversations/bob", user: "alice", want: 200},
+		{name: "show group conversation", method: "GET", path: "/conversations/friends", user: "alice", want: 200},
+		{name: "show group conversation, not a member", method: "GET", path: "/conversations/friends", user: "carol", want: 403},
+		{name: "show conversation, unknown partner", method: "GET", path: "/conversations/nobody", user: "alice", want: 404},
+		{name: "send message", method: "POST", path: "/conversations/bob", user: "alice", body: `{"message": "hi"}`, want: 200},
+		{name: "send group message", method: "POST", path: "/conversations/friends", user: "bob", body: `{"message": "hi"}`, want: 200},
+		{name: "send group message, not a member", method: "POST", path: "/conversations/friends", user: "carol", body: `{"message": "hi"}`, want: 403},
+		{name: "send message, unknown partner", method: "POST", path: "/conversations/nobody", user: "alice", body: `{"message": "hi"}`, want: 404},
+		{name: "mark received", method: "POST", path: "/conversations/bob/received", user: "alice", want: 200},
+		{name: "mark received, not a member", method: "POST", path: "/conversations/friends/received", user: "carol", want: 403},
+		{name: "mark received, unknown partner", method: "POST", path: "/conversations/nobody/received", user: "alice", want: 404},
+		{name: "mark read", method: "POST", path: "/conversations/bob/read", user: "alice", want: 200},
+		{name: "mark read, not a member", method: "POST", path: "/conversations/friends/read", user: "carol", want: 403},
+		{name: "mark read, unknown partner", method: "POST", path: "/conversations/nobody/read", user: "alice", want: 404},
+		{name: "conversation settings", method: "PUT", path: "/conversation-settings/bob", user: "alice", body: `{"archived": true, "muted_until": "2100-01-01T00:00:00Z", "pin_order": 0}`, want: 200},
+		{name: "conversation settings, group", method: "PUT", path: "/conversation-settings/friends", user: "bob", body: `{}`, want: 200},
+		{name: "conversation settings, invalid pin order", method: "PUT", path: "/conversation-settings/bob", user: "alice", body: `{"pin_order": -1}`, want: 400},
+		{name: "conversation settings, invalid body", method: "PUT", path: "/conversation-settings/bob", user: "alice", body: `{"muted_until": "tomorrow"}`, want: 400},
+		{name: "conversation settings, no conversation yet", method: "PUT", path: "/conversation-settings/carol", user: "alice", body: `{}`, want: 404},
+		{name: "conversation settings, not a member", method: "PUT", path: "/conversation-settings/friends", user: "carol", body: `{}`, want: 403},
+		{name: "conversation settings, unknown partner", method: "PUT", path: "/conversation-settings/nobody", user: "alice", body: `{}`, want: 404},
+
+		// Messages: messages of conversations the user is not part of do not exist for the user
+		{name: "forward", method: "POST", path: "/conversations/bob/messages/1", user: "alice", body: `{"recipientUsernames": ["friends", "carol"]}`, want: 200},
+		{name: "forward, group message", method: "POST", path: "/conversations/friends/messages/2", user: "alice", body: `{"recipientUsername": "carol"}`, want: 200},
+		{name: "forward, other conversation", method: "POST", path: "/conversations/bob/messages/3", user: "alice", body: `{"recipientUsername": "carol"}`, want: 404},
+		{name: "forward, wrong partner", method: "POST", path: "/conversations/friends/messages/1", user: "alice", body: `{"recipientUsername": "carol"}`, want: 404},
+		{name: "forward, unknown message", method: "POST", path: "/conversations/bob/messages/99", user: "alice", body: `{"recipientUsername": "carol"}`, want: 404},
+		{name: "forward, invalid message id", method: "POST", path: "/conversations/bob/messages/x", user: "alice", body: `{"recipientUsername": "carol"}`, want: 400},
+		{name: "edit message", method: "PUT", path: "/conversations/messages/1", user: "alice", body: `{"message": "edited"}`, want: 200},
+		{name: "edit message, not the sender", method: "PUT", path: "/conversations/messages/1", user: "bob", body: `{"message": "edited"}`, want: 403},
+		{name: "edit message, other conversation", method: "PUT", path: "/conversations/messages/1", user: "carol", body: `{"message": "edited"}`, want: 404},
+		{name: "edit message, unknown message", method: "PUT", path: "/conversations/messages/99", user: "alice", body: `{"message": "edited"}`, want: 404},
+		{name: "edit message, invalid message id", method: "PUT", path: "/conversations/messages/x", user: "alice", body: `{"message": "edited"}`, want: 400},
+		{name: "delete message", method: "DELETE", path: "/conversations/messages/1", user: "alice", want: 200},
+		{name: "delete message, not the sender", method: "DELETE", path: "/conversations/messages/1", user: "bob", want: 403},
+		{name: "delete message, other conversation", method: "DELETE", path: "/conversations/messages/1", user: "carol", want: 404},
+		{name: "delete message, unknown message", method: "DELETE", path: "/conversations/messages/99", user: "alice", want: 404},
+		{name: "delete message for me", method: "DELETE", path: "/conversations/messages/1?scope=me", user: "bob", want: 200},
+		{name: "delete message for me, other conversation", method: "DELETE", path: "/conversations/messages/1?scope=me", user: "carol", want: 404},
+		{name: "delete message, invalid scope", method: "DELETE", path: "/conversations/messages/1?scope=all", user: "alice", want: 400},
+		{name: "message edits", method: "GET", path: "/messages/1/edits", user: "bob", want: 200},
+		{name: "message edits, other conversation", method: "GET", path: "/messages/1/edits", user: "carol", want: 404},
+		{name: "message edits, unknown message", method: "GET", path: "/messages/99/edits", user: "alice", want: 404},
+		{name: "message receipts", method: "GET", path: "/messages/2/receipts", user: "alice", want: 200},
+		{name: "message receipts, other conversation", method: "GET", path: "/messages/2/receipts", user: "carol", want: 404},
+		{name: "message receipts, sender who left", method: "GET", path: "/messages/4/receipts", user: "carol", want: 200},
+		{name: "message edits, sender who left", method: "GET", path: "/messages/4/edits", user: "carol", want: 404},
+		{name: "message receipts, unknown message", method: "GET", path: "/messages/99/receipts", user: "alice", want: 404},
+
+		// Comments
+		{name: "comment", method: "PUT", path: "/conversations/messages/2/comment", user: "alice", body: `{"content": "👍"}`, want: 201},
+		{name: "comment, emoji sequence", method: "PUT", path: "/conversations/messages/2/comment", user: "alice", body: `{"content": "\ud83d\udc69\ud83c\udffd\u200d\ud83d\udcbb"}`, want: 201},
+		{name: "comment, flag", method: "PUT", path: "/conversations/messages/2/comment", user: "alice", body: `{"content": "🇮🇹"}`, want: 201},
+		{name: "comment, not an emoji", method: "PUT", path: "/conversations/messages/2/comment", user: "alice", body: `{"content": ":)"}`, want: 400},
+		{name: "comment, two emojis", method: "PUT", path: "/conversations/messages/2/comment", user: "alice", body: `{"content": "👍👍"}`, want: 400},
+		{name: "comment, other conversation", method: "PUT", path: "/conversations/messages/2/comment", user: "carol", body: `{"content": "👍"}`, want: 404},
+		{name: "comment, unknown message", method: "PUT", path: "/conversations/messages/99/comment", user: "alice", body: `{"content": "👍"}`, want: 404},
+		{name: "delete comment", method: "DELETE", path: "/conversations/messages/2/comment?content=%F0%9F%91%8D", user: "alice", want: 200},
+		{name: "delete all comments", method: "DELETE", path: "/conversations/messages/2/comment", user: "alice", want: 200},
+		{name: "delete comment, not reacted", method: "DELETE", path: "/conversations/messages/2/comment?content=%F0%9F%91%BB", user: "alice", want: 404},
+		{name: "delete comment, other conversation", method: "DELETE", path: "/conversations/messages/3/comment", user: "alice", want: 404},
+
+		// Groups: the database checks the role of the user in the group
+		{name: "add to group", method: "POST", path: "/groups", user: "alice", body: `{"groupName": "friends", "names": ["carol"]}`, want: 200},
+		{name: "add to group, not a member", method: "POST", path: "/groups", user: "carol", body: `{"groupName": "friends", "names": ["carol"]}`, want: 403},
+		{name: "get group", method: "GET", path: "/groups/friends", user: "bob", want: 200},
+		{name: "get group, not a member", method: "GET", path: "/groups/friends", user: "carol", want: 403},
+		{name: "get group, unknown group", method: "GET", path: "/groups/nothing", user: "alice", want: 404},
+		{name: "rename group", method: "PUT", path: "/groups/friends", user: "alice", body: `{"newGroupName": "buddies"}`, want: 200},
+		{name: "rename group, not an admin", method: "PUT", path: "/groups/friends", user: "bob", body: `{"newGroupName": "buddies"}`, want: 403},
+		{name: "rename group, not a member", method: "PUT", path: "/groups/friends", user: "carol", body: `{"newGroupName": "buddies"}`, want: 403},
+		{name: "leave group", method: "DELETE", path: "/groups/friends", user: "bob", want: 200},
+		{name: "leave group, not a member", method: "DELETE", path: "/groups/friends", user: "carol", want: 403},
+		{name: "change group photo", method: "PUT", path: "/groups/friends/group-photo", user: "alice", body: `{"newPhotoURL": "https://example.com/g.png"}`, want: 200},
+		{name: "change group photo, not a member", method: "PUT", path: "/groups/friends/group-photo", user: "carol", body: `{"newPhotoURL": "https://example.com/g.png"}`, want: 403},
+		{name: "set role", method: "PUT", path: "/groups/friends/members/bob/role", user: "alice", body: `{"role": "admin"}`, want: 200},
+		{name: "set role, not the owner", method: "PUT", path: "/groups/friends/members/alice/role", user: "bob", body: `{"role": "member"}`, want: 403},
+		{name: "remove member", method: "DELETE", path: "/groups/friends/members/bob", user: "alice", want: 200},
+		{name: "remove member, not a member", method: "DELETE", path: "/groups/friends/members/bob", user: "carol", want: 403},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := do(t, server, tc.method, tc.path, tc.user, tc.body, tc.contentType)
+			if got != tc.want {
+				t.Errorf("%s %s as %q: got status %d, want %d", tc.method, tc.path, tc.user, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestRoutesRequireSession checks that the protected routes refuse requests without a valid session, before any
+// other check.
+func TestRoutesRequireSession(t *testing.T) {
+	_, server := newTestServer(t)
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{"DELETE", "/session"},
+		{"POST", "/upload"},
+		{"GET", "/events"},
+		{"GET", "/users"},
+		{"GET", "/users/bob"},
+		{"GET", "/search/messages"},
+		{"PUT", "/user-profile"},
+		{"GET", "/user-profile"},
+		{"PUT", "/profile-picture"},
+		{"PUT", "/password"},
+		{"GET", "/conversations/bob"},
+		{"POST", "/conversations/bob"},
+		{"POST", "/conversations/bob/messages/1"},
+		{"POST", "/conversations/bob/received"},
+		{"POST", "/conversations/bob/read"},
+		{"PUT", "/conversation-settings/bob"},
+		{"PUT", "/conversations/messages/1"},
+		{"DELETE", "/conversations/messages/1"},
+		{"GET", "/messages/1/edits"},
+		{"GET", "/messages/1/receipts"},
+		{"PUT", "/conversations/messages/1/comment"},
+		{"DELETE", "/conversations/messages/1/comment"},
+		{"POST", "/groups"},
+		{"GET", "/groups/friends"},
+		{"PUT", "/groups/friends"},
+		{"DELETE", "/groups/friends"},
+		{"PUT", "/groups/friends/group-photo"},
+		{"PUT", "/groups/friends/members/bob/role"},
+		{"DELETE", "/groups/friends/members/bob"},
+	}
+
+	for _, route := range routes {
+		route := route
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			if got := do(t, server, route.method, route.path, "", "", ""); got != http.StatusUnauthorized {
+				t.Errorf("without session: got status %d, want 401", got)
+			}
+			if got := do(t, server, route.method, route.path, "mallory", "", ""); got != http.StatusUnauthorized {
+				t.Errorf("with an invalid session: got status %d, want 401", got)
+			}
+		})
+	}
+}
+
+// TestLoginRefusesAccountsWithoutPassword checks that, when passwords are required, a login can't claim an existing
+// account without password, whose user must set one from a session instead.
+func TestLoginRefusesAccountsWithoutPassword(t *testing.T) {
+	rt, server := newTestServer(t)
+	rt.requirePassword = true
+
+	if got := do(t, server, "POST", "/session", "", `{"username": "alice", "password": "correct horse"}`, ""); got != http.StatusForbidden {
+		t.Errorf("login of a user without password: got status %d, want 403", got)
+	}
+	if got := do(t, server, "POST", "/session", "", `{"username": "dave", "password": "correct horse"}`, ""); got != http.StatusOK {
+		t.Errorf("login of a new user: got status %d, want 200", got)
+	}
+	if got := do(t, server, "PUT", "/password", "alice", `{"password": "correct horse"}`, ""); got != http.StatusOK {
+		t.Errorf("setting the password from a session: got status %d, want 200", got)
+	}
+}
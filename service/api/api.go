@@ -9,8 +9,17 @@ Example:
 
 	// Create the API router
 	apirouter, err := api.New(api.Config{
-		Logger:   logger,
-		Database: appdb,
+		Logger:              logger,
+		Database:            appdb,
+		SessionTTL:          cfg.Auth.SessionTTL,
+		RequirePassword:     cfg.Auth.RequirePassword,
+		MessageEditWindow:   cfg.Messages.EditWindow,
+		MessageDeleteWindow: cfg.Messages.DeleteWindow,
+		Media:               storage,
+		MediaURLKey:         []byte(cfg.Media.URLKey),
+		MediaURLTTL:         cfg.Media.URLTTL,
+		MediaGCInterval:     cfg.Media.GCInterval,
+		MediaGCGracePeriod:  cfg.Media.GCGracePeriod,
 	})
 	if err != nil {
 		logger.WithError(err).Error("error creating the API server instance")
@@ -37,11 +46,16 @@ See the `main.go` file inside the `cmd/webapi` for a full usage example.
 package api
 
 import (
+	"crypto/rand"
 	"errors"
+	"fmt"
 	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
+	"github.com/DavideStummSapienza/WASAText/service/media"
 	"github.com/julienschmidt/httprouter"
 	"github.com/sirupsen/logrus"
 	"net/http"
+	"time"
 )
 
 // Config is used to provide dependencies and configuration to the New function.
@@ -51,6 +65,34 @@ type Config struct {
 
 	// Database is the instance of database.AppDatabase where data are saved
 	Database database.AppDatabase
+
+	// SessionTTL is how long a session token stays valid after login
+	SessionTTL time.Duration
+
+	// MessageEditWindow is how long after sending a message its sender can edit it
+	MessageEditWindow time.Duration
+
+	// MessageDeleteWindow is how long after sending a message its sender can delete it for everyone
+	MessageDeleteWindow time.Duration
+
+	// RequirePassword makes POST /session require a password: the first login of a new user sets it, the following
+	// ones must present it. Existing users without a password must set one with PUT /password before logging in.
+	RequirePassword bool
+
+	// Media is where uploaded files are stored
+	Media media.Storage
+
+	// MediaURLKey is the secret key signing the URLs of the photos sent in chats. If empty, a random key is generated,
+	// and the URLs signed before a restart stop working.
+	MediaURLKey []byte
+
+	// MediaURLTTL is how long the signed URL of a photo sent in a chat stays valid
+	MediaURLTTL time.Duration
+
+	// MediaGCInterval is how often uploaded files not used anywhere are looked for and removed. Files are removed only
+	// when they have not been used for MediaGCGracePeriod, which leaves the clients the time to use a new upload.
+	MediaGCInterval    time.Duration
+	MediaGCGracePeriod time.Duration
 }
 
 // Router is the package API interface representing an API handler builder
@@ -71,6 +113,34 @@ func New(cfg Config) (Router, error) {
 	if cfg.Database == nil {
 		return nil, errors.New("database is required")
 	}
+	if cfg.SessionTTL <= 0 {
+		return nil, errors.New("session TTL must be positive")
+	}
+	if cfg.MessageEditWindow < 0 {
+		return nil, errors.New("message edit window can't be negative")
+	}
+	if cfg.MessageDeleteWindow < 0 {
+		return nil, errors.New("message delete window can't be negative")
+	}
+	if cfg.Media == nil {
+		return nil, errors.New("media storage is required")
+	}
+	if cfg.MediaURLTTL <= 0 {
+		return nil, errors.New("media URL TTL must be positive")
+	}
+	if cfg.MediaGCInterval <= 0 {
+		return nil, errors.New("media GC interval must be positive")
+	}
+	if cfg.MediaGCGracePeriod < 0 {
+		return nil, errors.New("media GC grace period can't be negative")
+	}
+	mediaURLKey := cfg.MediaURLKey
+	if len(mediaURLKey) == 0 {
+		mediaURLKey = make([]byte, 32)
+		if _, err := rand.Read(mediaURLKey); err != nil {
+			return nil, fmt.Errorf("error generating media URL key: %w", err)
+		}
+	}
 
 	// Create a new router where we will register HTTP endpoints. The server will pass requests to this router to be
 	// handled.
@@ -78,11 +148,22 @@ func New(cfg Config) (Router, error) {
 	router.RedirectTrailingSlash = false
 	router.RedirectFixedPath = false
 
-	return &_router{
-		router:     router,
-		baseLogger: cfg.Logger,
-		db:         cfg.Database,
-	}, nil
+	rt := &_router{
+		router:              router,
+		baseLogger:          cfg.Logger,
+		db:                  cfg.Database,
+		hub:                 events.NewHub(),
+		sessionTTL:          cfg.SessionTTL,
+		requirePassword:     cfg.RequirePassword,
+		loginLimiter:        newLoginLimiter(),
+		messageEditWindow:   cfg.MessageEditWindow,
+		messageDeleteWindow: cfg.MessageDeleteWindow,
+		media:               cfg.Media,
+		mediaSigner:         media.NewSigner(mediaURLKey),
+		mediaURLTTL:         cfg.MediaURLTTL,
+	}
+	rt.mediaJanitor = startMediaJanitor(rt, cfg.MediaGCInterval, cfg.MediaGCGracePeriod)
+	return rt, nil
 }
 
 type _router struct {
@@ -93,4 +174,32 @@ type _router struct {
 	baseLogger logrus.FieldLogger
 
 	db database.AppDatabase
+
+	// sessionTTL is the lifetime of new sessions
+	sessionTTL time.Duration
+
+	// requirePassword enables password checks on login
+	requirePassword bool
+
+	// loginLimiter throttles failed login attempts
+	loginLimiter *loginLimiter
+
+	// messageEditWindow is how long messages can be edited after sending
+	messageEditWindow time.Duration
+
+	// messageDeleteWindow is how long messages can be deleted for everyone after sending
+	messageDeleteWindow time.Duration
+
+	// hub dispatches real-time events to the clients connected to the event stream
+	hub *events.Hub
+
+	// media stores the uploaded files
+	media media.Storage
+
+	// mediaSigner signs the URLs of the photos sent in chats, which are valid for mediaURLTTL
+	mediaSigner *media.Signer
+	mediaURLTTL time.Duration
+
+	// mediaJanitor removes the uploaded files not used anymore, it is stopped by Close
+	mediaJanitor *mediaJanitor
 }
@@ -20,18 +20,15 @@ const usernameKey contextKey = "username"
 func AuthMiddleware(db database.AppDatabase, next httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		// Check for the presence of the Authorization header.
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		token, ok := bearerToken(r)
+		if !ok {
 			// If the header is missing or improperly formatted, respond with an error.
 			w.Header().Set("Content-Type", "application/json")
 			http.Error(w, `{"error": "missing or invalid token"}`, http.StatusUnauthorized)
 			return
 		}
 
-		// Extract the token by removing the "Bearer " prefix.
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-
-		// Look up the username associated with the token in the database.
+		// Look up the username associated with the session token in the database.
 		username, err := db.GetUsernameByToken(token)
 		if err != nil {
 			// If there is an error querying the database, respond with an internal server error.
@@ -54,3 +51,15 @@ func AuthMiddleware(db database.AppDatabase, next httprouter.Handle) httprouter.
 		next(w, r, ps)
 	}
 }
+
+// bearerToken extracts the token from the Authorization header ("Bearer <token>").
+func bearerToken(r *http.Request) (string, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", false
+	}
+
+	// Extract the token by removing the "Bearer " prefix.
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	return token, token != ""
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Keys of the values stored in the request context by the access wrappers below.
+const (
+	// conversationKey is the *database.ConversationMembers of the conversation the request is about
+	conversationKey contextKey = "conversation"
+
+	// messageIDKey is the ID of the message the request is about
+	messageIDKey contextKey = "message-id"
+)
+
+// Errors of the access checks, written by writeAccessError.
+var (
+	errPartnerNotFound = errors.New("conversation partner not found")
+	errNotParticipant  = errors.New("user is not part of the conversation")
+	errMessageNotFound = errors.New("message not found")
+)
+
+// wrapWithConversationAccess applies the authentication middleware, then checks that the user takes part in the
+// conversation with the `partner-username` URL parameter: the other user of a 1:1 conversation, or a group the user
+// is a member of. The participants are stored in the request context, see contextConversation.
+//
+// It responds with 404 Not Found if the partner is neither a user nor a group, and with 403 Forbidden if it is a
+// group the user is not a member of.
+func (rt *_router) wrapWithConversationAccess(handle httprouter.Handle) httprouter.Handle {
+	return rt.wrapWithAuth(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		username := r.Context().Value(usernameKey).(string)
+
+		members, err := rt.conversationAccess(username, ps.ByName("partner-username"))
+		if err != nil {
+			writeAccessError(w, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), conversationKey, members)
+		handle(w, r.WithContext(ctx), ps)
+	})
+}
+
+// wrapWithMessageAccess applies the authentication middleware, then checks that the user takes part in the
+// conversation of the message in the `message-id` URL parameter. The message ID and the participants are stored in
+// the request context, see contextMessageID and contextConversation.
+//
+// It responds with 400 Bad Request if the message ID is invalid, and with 404 Not Found if the message does not exist
+// or the user is not part of its conversation: a user can't learn whether messages of other conversations exist.
+// Whether the user can act on the message (e.g., edit it) is up to the handler.
+func (rt *_router) wrapWithMessageAccess(handle httprouter.Handle) httprouter.Handle {
+	return rt.wrapWithMessageAccessFor(false, handle)
+}
+
+// wrapWithSenderMessageAccess is wrapWithMessageAccess, except that the sender of the message also passes after
+// leaving its conversation (e.g., to follow the receipts of a message sent to a group before leaving it).
+func (rt *_router) wrapWithSenderMessageAccess(handle httprouter.Handle) httprouter.Handle {
+	return rt.wrapWithMessageAccessFor(true, handle)
+}
+
+// wrapWithMessageAccessFor implements wrapWithMessageAccess and wrapWithSenderMessageAccess.
+func (rt *_router) wrapWithMessageAccessFor(allowSender bool, handle httprouter.Handle) httprouter.Handle {
+	return rt.wrapWithAuth(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		username := r.Context().Value(usernameKey).(string)
+
+		id, err := strconv.Atoi(ps.ByName("message-id"))
+		if err != nil || id <= 0 {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error": "invalid message-id format"}`, http.StatusBadRequest)
+			return
+		}
+
+		members, err := rt.messageAccess(username, id, allowSender)
+		if err != nil {
+			writeAccessError(w, err)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), conversationKey, members)
+		ctx = context.WithValue(ctx, messageIDKey, id)
+		handle(w, r.WithContext(ctx), ps)
+	})
+}
+
+// conversationAccess returns the participants of the conversation of `username` with `partner`, errPartnerNotFound
+// if the partner does not exist, or errNotParticipant if the user is not a member of the partner group.
+func (rt *_router) conversationAccess(username, partner string) (*database.ConversationMembers, error) {
+	members, err := rt.db.GetConversationMembers(username, partner)
+	if err != nil {
+		return nil, err
+	}
+
+	if members.Groupname == "" {
+		// A 1:1 conversation, with a user that must exist
+		_, err = rt.db.GetUser(partner)
+		if errors.Is(err, database.ErrUserNotFound) {
+			return nil, errPartnerNotFound
+		} else if err != nil {
+			return nil, err
+		}
+		return members, nil
+	}
+
+	if !isMember(members, username) {
+		return nil, errNotParticipant
+	}
+	return members, nil
+}
+
+// messageAccess returns the participants of the conversation of the message, or errMessageNotFound if the message
+// does not exist or the user is not one of them, nor its sender when `allowSender` is set.
+func (rt *_router) messageAccess(username string, messageID int, allowSender bool) (*database.ConversationMembers, error) {
+	members, err := rt.db.GetMessageConversationMembers(messageID)
+	if errors.Is(err, database.ErrMessageNotFound) {
+		return nil, errMessageNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if isMember(members, username) {
+		return members, nil
+	}
+
+	if allowSender {
+		message, err := rt.db.GetMessage(&messageID, username)
+		if err != nil {
+			return nil, err
+		}
+		if message.Sender == username {
+			return members, nil
+		}
+	}
+	return nil, errMessageNotFound
+}
+
+// writeAccessError responds with the status of an error of the access checks.
+func writeAccessError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, errPartnerNotFound), errors.Is(err, errMessageNotFound):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+	case errors.Is(err, errNotParticipant):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusForbidden)
+	default:
+		http.Error(w, `{"error": "failed to check access"}`, http.StatusInternalServerError)
+	}
+}
+
+// contextConversation returns the participants stored in the request context by wrapWithConversationAccess or
+// wrapWithMessageAccess.
+func contextConversation(r *http.Request) *database.ConversationMembers {
+	members, _ := r.Context().Value(conversationKey).(*database.ConversationMembers)
+	return members
+}
+
+// contextMessageID returns the message ID stored in the request context by wrapWithMessageAccess.
+func contextMessageID(r *http.Request) int {
+	id, _ := r.Context().Value(messageIDKey).(int)
+	return id
+}
+
+// isMember reports whether the user takes part in the conversation.
+func isMember(members *database.ConversationMembers, username string) bool {
+	for _, member := range members.Usernames {
+		if member == username {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -19,7 +20,7 @@ type ChangeGroupNameResponse struct {
 	Message string `json:"message"`
 }
 
-// changeGroupName handles the HTTP request to change a group name
+// changeGroupName handles the HTTP request to change a group name. Only the owner and the admins can rename a group.
 func (rt *_router) changeGroupName(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -69,13 +70,16 @@ func (rt *_router) changeGroupName(w http.ResponseWriter, r *http.Request, ps ht
 	}
 
 	// Call the database function to change the group name
-	err = rt.db.ChangeGroupName(oldGroupName, req.NewGroupName)
+	err = rt.db.ChangeGroupName(username, oldGroupName, req.NewGroupName)
 	if err != nil {
-		// If the database function returns an error, respond with a 400 Bad Request error
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		// If the database function returns an error, respond with 404/403 or a 400 Bad Request error
+		writeGroupError(w, err, http.StatusBadRequest)
 		return
 	}
 
+	// Notify the group members.
+	rt.notifyConversation(username, req.NewGroupName, events.Event{Type: events.GroupChanged, Actor: username})
+
 	// Send a success response
 	response := ChangeGroupNameResponse{Message: "Group name successfully changed"}
 	w.WriteHeader(http.StatusOK)
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -17,7 +18,7 @@ type ChangeGroupPictureResponse struct {
 	Message string `json:"message"`
 }
 
-// changeGroupPicture handles updating a group's profile picture
+// changeGroupPicture handles updating a group's profile picture. Only the owner and the admins can change it.
 func (rt *_router) changeGroupPicture(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -43,18 +44,21 @@ func (rt *_router) changeGroupPicture(w http.ResponseWriter, r *http.Request, ps
 	}
 
 	// Validate the new photo URL
-	if err := validateURL(req.NewPhotoURL); err != nil {
+	if err := rt.validateURL(req.NewPhotoURL); err != nil {
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
 		return
 	}
 
 	// Call database function to update group picture
-	err := rt.db.ChangeGroupPicture(groupName, req.NewPhotoURL)
+	err := rt.db.ChangeGroupPicture(username, groupName, req.NewPhotoURL)
 	if err != nil {
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		writeGroupError(w, err, http.StatusBadRequest)
 		return
 	}
 
+	// Notify the group members.
+	rt.notifyConversation(username, groupName, events.Event{Type: events.GroupChanged, Actor: username})
+
 	// Send success response
 	response := ChangeGroupPictureResponse{Message: "Group picture successfully updated"}
 	w.WriteHeader(http.StatusOK)
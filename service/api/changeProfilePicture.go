@@ -58,7 +58,7 @@ func (rt *_router) changeProfilePicture(w http.ResponseWriter, r *http.Request,
 	}
 
 	// Validate the photo URL
-	if err := validateURL(request.PhotoURL); err != nil {
+	if err := rt.validateURL(request.PhotoURL); err != nil {
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
 		return
 	}
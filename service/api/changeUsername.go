@@ -92,6 +92,9 @@ func (rt *_router) changeUsername(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
+	// Keep the open event streams of the user working under the new name
+	rt.hub.Rename(oldUsername, request.NewUsername)
+
 	// Create a response with a success message and the updated username
 	response := ChangeUsernameResponse{
 		Message:     "username successfully changed",
@@ -2,18 +2,21 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
 
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
-// MakeCommentResponse represents the response structure after a successful comment creation.
+// DeleteCommentResponse represents the response structure after a successful comment deletion.
 type DeleteCommentResponse struct {
 	Message string `json:"message"`
 }
 
-// deleteComment handles deleting a user's comment on a message.
+// deleteComment handles deleting a user's comment on a message. The `content` query parameter selects the reaction to
+// remove; without it, all the user's reactions to the message are removed.
 //
 // Parameters:
 // - w: HTTP response writer
@@ -24,6 +27,8 @@ type DeleteCommentResponse struct {
 // - 200 OK if the comment is successfully deleted.
 // - 400 Bad Request if the message ID is missing or invalid.
 // - 401 Unauthorized if the user is not authenticated.
+// - 404 Not Found if the message does not exist, the user does not take part in its conversation, or has no such
+// comment on it.
 // - 500 Internal Server Error if database operation fails.
 func (rt *_router) deleteComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
@@ -35,32 +40,24 @@ func (rt *_router) deleteComment(w http.ResponseWriter, r *http.Request, ps http
 		return
 	}
 
-	// 2️. Get message ID from query parameter
-	messageIDStr := ps.ByName("message-id")
-	if messageIDStr == "" {
-		http.Error(w, `{"error": "missing message-id parameter"}`, http.StatusBadRequest)
-		return
-	}
-
-	// Convert messageID to an integer
-	messageID, err := strconv.Atoi(messageIDStr)
-	if err != nil {
-		http.Error(w, `{"error": "invalid message-id format"}`, http.StatusBadRequest)
-		return
-	}
+	// 2️. The message, which the user can see (checked by wrapWithMessageAccess)
+	messageID := contextMessageID(r)
 
 	// 3️. Attempt to delete the comment from the database
-	err = rt.db.DeleteComment(messageID, username)
+	err := rt.db.DeleteComment(messageID, username, r.URL.Query().Get("content"))
 	if err != nil {
-		if err.Error() == "comment not found or user not authorized" {
-			http.Error(w, `{"error": "comment not found or unauthorized"}`, http.StatusNotFound)
+		if errors.Is(err, database.ErrCommentNotFound) {
+			http.Error(w, `{"error": "comment not found"}`, http.StatusNotFound)
 			return
 		}
 		http.Error(w, `{"error": "failed to delete comment"}`, http.StatusInternalServerError)
 		return
 	}
 
-	// 4. Return success response.
+	// Notify the participants of the conversation.
+	rt.notifyMessage(messageID, events.Event{Type: events.CommentDeleted, Actor: username, MessageID: messageID})
+
+	// 5. Return success response.
 	response := DeleteCommentResponse{Message: "Comment deleted successfully"}
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
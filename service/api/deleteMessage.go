@@ -2,9 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
 
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -13,8 +15,19 @@ type DeleteMessageResponse struct {
 	Message string `json:"message"`
 }
 
+// Values of the `scope` query parameter of deleteMessage
+const (
+	deleteForEveryone = "everyone"
+	deleteForMe       = "me"
+)
+
 // deleteMessage handles the deletion of a specific message by ID.
 //
+// With `scope=everyone` (the default), the message is deleted for every participant and replaced with a tombstone.
+// Senders can delete their own messages within the delete window; group owners and admins can delete any message of
+// the group at any time. With `scope=me`, any participant can delete any message for themselves only: it is hidden
+// from their view of the conversation.
+//
 // Parameters:
 // - w: HTTP response writer
 // - r: HTTP request
@@ -24,7 +37,9 @@ type DeleteMessageResponse struct {
 // - 200 OK if the deletion is successful.
 // - 400 Bad Request if required parameters are missing/invalid.
 // - 401 Unauthorized if the user is not authenticated.
-// - 404 Not Found if the message does not exist or the user lacks permissions.
+// - 403 Forbidden if the user is not allowed to delete the message for everyone, or no longer.
+// - 404 Not Found if the message does not exist or the user does not take part in its conversation.
+// - 409 Conflict if the message is already deleted for everyone.
 // - 500 Internal Server Error if the deletion process fails.
 func (rt *_router) deleteMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
@@ -36,26 +51,52 @@ func (rt *_router) deleteMessage(w http.ResponseWriter, r *http.Request, ps http
 		return
 	}
 
-	// 2. Extract `messageID` from the URL and convert it to an integer
-	messageIDStr := ps.ByName("message-id")
-	messageID, err := strconv.Atoi(messageIDStr)
-	if err != nil {
-		http.Error(w, `{"error": "invalid message-id format"}`, http.StatusBadRequest)
+	// 2. The message, which the user can see, and the participants to notify afterwards (resolved by
+	// wrapWithMessageAccess)
+	messageID := contextMessageID(r)
+	members := contextConversation(r)
+
+	// 3. Delete the message from the database, for everyone or only for the user
+	var err error
+	scope := r.URL.Query().Get("scope")
+	switch scope {
+	case "", deleteForEveryone:
+		err = rt.db.DeleteMessage(username, messageID, rt.messageDeleteWindow)
+	case deleteForMe:
+		err = rt.db.HideMessage(username, messageID)
+	default:
+		http.Error(w, `{"error": "scope must be 'everyone' or 'me'"}`, http.StatusBadRequest)
 		return
 	}
-
-	// 3. Delete the message from the database
-	err = rt.db.DeleteMessage(username, messageID)
-	if err != nil {
-		if err.Error() == "message not found or no permissions to delete" {
-			http.Error(w, `{"error": "message not found or not authorized"}`, http.StatusNotFound)
-			return
-		}
+	switch {
+	case errors.Is(err, database.ErrMessageNotFound):
+		http.Error(w, `{"error": "message not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, database.ErrNotMessageSender):
+		http.Error(w, `{"error": "not authorized to delete this message"}`, http.StatusForbidden)
+		return
+	case errors.Is(err, database.ErrDeleteWindowExpired):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusForbidden)
+		return
+	case errors.Is(err, database.ErrMessageDeleted):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+		return
+	case err != nil:
 		http.Error(w, `{"error": "failed to delete message"}`, http.StatusInternalServerError)
 		return
 	}
 
-	// 4. Return a success response
+	// 4. Notify the participants, or only the other clients of the user if the message is hidden for them alone
+	ev := events.Event{Type: events.MessageDeleted, Actor: username, MessageID: messageID}
+	if scope == deleteForMe {
+		ev.Conversation = conversationNameFor(members, username)
+		ev.IsGroup = members.Groupname != ""
+		rt.hub.Publish(username, ev)
+	} else {
+		rt.publish(members, ev)
+	}
+
+	// 5. Return a success response
 	response := DeleteMessageResponse{Message: "Message deleted successfully"}
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
+	"github.com/julienschmidt/httprouter"
+)
+
+// EditMessageRequest represents the expected structure of the request body.
+type EditMessageRequest struct {
+	Message string `json:"message"` // The new text of the message
+}
+
+// editMessage handles the edit of the text of a message by its sender.
+//
+// Parameters:
+// - w: HTTP response writer
+// - r: HTTP request
+// - ps: Route parameters (contains message-id)
+//
+// Returns:
+// - 200 OK with the updated message if the edit is successful.
+// - 400 Bad Request if the message-id or the new text is missing/invalid.
+// - 401 Unauthorized if the user is not authenticated.
+// - 403 Forbidden if the user is not the sender, the message is a photo, or the edit window is over.
+// - 404 Not Found if the message does not exist or the user does not take part in its conversation.
+// - 409 Conflict if the message was deleted for everyone.
+// - 500 Internal Server Error if the edit fails.
+func (rt *_router) editMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 1. Extract the authenticated username from the request context
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// 2. The message, which the user can see (checked by wrapWithMessageAccess)
+	messageID := contextMessageID(r)
+
+	// 3. Decode and validate the request body
+	var req EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Message == "" {
+		http.Error(w, `{"error": "invalid or missing message"}`, http.StatusBadRequest)
+		return
+	}
+
+	// 4. Edit the message in the database
+	err := rt.db.EditMessage(username, messageID, req.Message, rt.messageEditWindow)
+	switch {
+	case errors.Is(err, database.ErrMessageNotFound):
+		http.Error(w, `{"error": "message not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, database.ErrNotMessageSender),
+		errors.Is(err, database.ErrPhotoMessage),
+		errors.Is(err, database.ErrEditWindowExpired):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusForbidden)
+		return
+	case errors.Is(err, database.ErrMessageDeleted):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+		return
+	case errors.Is(err, database.ErrMessageUnchanged):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, `{"error": "failed to edit message"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// 5. Notify the participants of the conversation
+	rt.notifyMessage(messageID, events.Event{Type: events.MessageEdited, Actor: username, MessageID: messageID})
+
+	// 6. Return the updated message
+	message, err := rt.db.GetMessage(&messageID, username)
+	if err != nil {
+		http.Error(w, `{"error": "failed to retrieve edited message"}`, http.StatusInternalServerError)
+		return
+	}
+	rt.signPhoto(message)
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(message); err != nil {
+		// Handle any potential error during JSON encoding.
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
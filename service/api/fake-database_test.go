@@ -0,0 +1,290 @@
+package api
+
+import (
+	"time"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+)
+
+// fakeDatabase is an in-memory database.AppDatabase with a fixed set of users, groups and messages, enough to drive
+// every route of the API. The embedded interface is nil: calling a method not implemented here panics, which fails
+// the test that reached it.
+//
+// Users: alice, bob and carol, whose session token is "token-" followed by the username.
+// Groups: friends, owned by alice, with bob as member.
+// Messages: 1 from alice to bob, 2 from bob in friends, 3 from carol to bob, 4 from carol in friends before leaving.
+type fakeDatabase struct {
+	database.AppDatabase
+}
+
+// Names used by the fake database
+const (
+	fakeGroup       = "friends"
+	fakePublicMedia = "0123456789abcdef.png"
+
+	// fakeMissingReaction is a reaction that no user made
+	fakeMissingReaction = "\U0001F47B"
+)
+
+var (
+	fakeUsers = []string{"alice", "bob", "carol"}
+
+	fakeGroupRoles = map[string]string{"alice": database.RoleOwner, "bob": database.RoleMember}
+
+	fakeMessages = map[int]struct {
+		sender  string
+		members database.ConversationMembers
+	}{
+		1: {"alice", database.ConversationMembers{Usernames: []string{"alice", "bob"}}},
+		2: {"bob", database.ConversationMembers{Groupname: fakeGroup, Usernames: []string{"alice", "bob"}}},
+		3: {"carol", database.ConversationMembers{Usernames: []string{"carol", "bob"}}},
+		4: {"carol", database.ConversationMembers{Groupname: fakeGroup, Usernames: []string{"alice", "bob"}}},
+	}
+)
+
+// groupRole returns the role of the user in a group, or the error of the database for outsiders.
+func (db *fakeDatabase) groupRole(groupname, username string) (string, error) {
+	if groupname != fakeGroup {
+		return "", database.ErrGroupNotFound
+	}
+	role, ok := fakeGroupRoles[username]
+	if !ok {
+		return "", database.ErrNotGroupMember
+	}
+	return role, nil
+}
+
+// requireGroupAdmin returns the error of the database if the user is not an admin of the group.
+func (db *fakeDatabase) requireGroupAdmin(groupname, username string) error {
+	role, err := db.groupRole(groupname, username)
+	if err != nil {
+		return err
+	}
+	if role != database.RoleOwner && role != database.RoleAdmin {
+		return database.ErrNotGroupAdmin
+	}
+	return nil
+}
+
+func (db *fakeDatabase) GetUsernameByToken(token string) (string, error) {
+	for _, username := range fakeUsers {
+		if token == "token-"+username {
+			return username, nil
+		}
+	}
+	return "", nil
+}
+
+func (db *fakeDatabase) CreateSession(string, string, time.Duration) error {
+	return nil
+}
+
+func (db *fakeDatabase) DeleteSession(string) error {
+	return nil
+}
+
+func (db *fakeDatabase) GetUser(username string) (*database.User, error) {
+	for _, u := range fakeUsers {
+		if u == username {
+			return &database.User{Username: username}, nil
+		}
+	}
+	return nil, database.ErrUserNotFound
+}
+
+func (db *fakeDatabase) CreateUser(string, string, string) error {
+	return nil
+}
+
+// GetPasswordHash returns no password: the fake users were created before passwords were required.
+func (db *fakeDatabase) GetPasswordHash(username string) (string, error) {
+	if _, err := db.GetUser(username); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+func (db *fakeDatabase) SetPasswordHash(username string, _ string) error {
+	_, err := db.GetUser(username)
+	return err
+}
+
+func (db *fakeDatabase) SearchUser(_ string, partialUsername string, _ int, _ int) ([]database.User, error) {
+	if partialUsername == "" {
+		return nil, database.ErrEmptyUserSearch
+	}
+	return []database.User{}, nil
+}
+
+func (db *fakeDatabase) LoadUserConversations(string, bool) ([]database.ConversationPreview, error) {
+	return []database.ConversationPreview{}, nil
+}
+
+func (db *fakeDatabase) SetConversationSettings(username, partnerName string, _ database.ConversationSettings) error {
+	for _, message := range fakeMessages {
+		members := message.members
+		if members.Groupname == partnerName || (members.Groupname == "" && isMember(&members, username) &&
+			isMember(&members, partnerName)) {
+			return nil
+		}
+	}
+	return database.ErrConversationNotFound
+}
+
+func (db *fakeDatabase) ChangeUsername(string, string) error {
+	return nil
+}
+
+func (db *fakeDatabase) ChangeProfilePicture(string, string) error {
+	return nil
+}
+
+func (db *fakeDatabase) GetGroupByName(groupName string) (*database.Group, error) {
+	if groupName != fakeGroup {
+		return nil, database.ErrGroupNotFound
+	}
+	return &database.Group{Groupname: groupName}, nil
+}
+
+func (db *fakeDatabase) CreateMedia(database.Media) error {
+	return nil
+}
+
+func (db *fakeDatabase) GetMedia(string) (*database.Media, error) {
+	return nil, database.ErrMediaNotFound
+}
+
+func (db *fakeDatabase) IsPublicMedia(name string) (bool, error) {
+	return name == fakePublicMedia, nil
+}
+
+func (db *fakeDatabase) ShowConversation(string, string, database.PageRequest) (*database.ConversationPage, error) {
+	return &database.ConversationPage{Messages: []database.ConversationDetail{}}, nil
+}
+
+func (db *fakeDatabase) SendMessage(database.NewMessage) (int, error) {
+	return 10, nil
+}
+
+func (db *fakeDatabase) ForwardMessage(_ string, _ int, recipients []string) ([]int, error) {
+	ids := make([]int, len(recipients))
+	for i := range recipients {
+		ids[i] = 11 + i
+	}
+	return ids, nil
+}
+
+func (db *fakeDatabase) DeleteMessage(currentUser string, messageID int, _ time.Duration) error {
+	if fakeMessages[messageID].sender != currentUser {
+		return database.ErrNotMessageSender
+	}
+	return nil
+}
+
+func (db *fakeDatabase) HideMessage(string, int) error {
+	return nil
+}
+
+func (db *fakeDatabase) EditMessage(currentUser string, messageID int, _ string, _ time.Duration) error {
+	if fakeMessages[messageID].sender != currentUser {
+		return database.ErrNotMessageSender
+	}
+	return nil
+}
+
+func (db *fakeDatabase) GetMessageEdits(int) ([]database.MessageEdit, error) {
+	return []database.MessageEdit{}, nil
+}
+
+func (db *fakeDatabase) GetMessageReceipts(int) ([]database.Receipt, error) {
+	return []database.Receipt{}, nil
+}
+
+func (db *fakeDatabase) MarkMessagesAsReceived(string, string, int) (int, error) {
+	return 0, nil
+}
+
+func (db *fakeDatabase) MarkMessagesAsRead(string, string, int) (int, error) {
+	return 0, nil
+}
+
+// GetMessage also returns the messages just sent or forwarded, which are not in fakeMessages, without sender.
+func (db *fakeDatabase) GetMessage(messageID *int, _ string) (*database.ConversationDetail, error) {
+	sender := fakeMessages[*messageID].sender
+	return &database.ConversationDetail{MessageID: *messageID, Sender: sender, Kind: database.MessageKindUser}, nil
+}
+
+func (db *fakeDatabase) GetConversationMembers(username, partnerName string) (*database.ConversationMembers, error) {
+	if partnerName == fakeGroup {
+		return &database.ConversationMembers{Groupname: fakeGroup, Usernames: []string{"alice", "bob"}}, nil
+	}
+	return &database.ConversationMembers{Usernames: []string{username, partnerName}}, nil
+}
+
+func (db *fakeDatabase) GetMessageConversationMembers(messageID int) (*database.ConversationMembers, error) {
+	message, ok := fakeMessages[messageID]
+	if !ok {
+		return nil, database.ErrMessageNotFound
+	}
+	members := message.members
+	members.Usernames = append([]string(nil), members.Usernames...)
+	return &members, nil
+}
+
+func (db *fakeDatabase) SearchMessages(_ string, query string, _ int, _ int) ([]database.MessageSearchHit, bool, error) {
+	if query == "" {
+		return nil, false, database.ErrEmptySearch
+	}
+	return []database.MessageSearchHit{}, false, nil
+}
+
+func (db *fakeDatabase) AddComment(int, string, string) error {
+	return nil
+}
+
+func (db *fakeDatabase) DeleteComment(_ int, _ string, content string) error {
+	if content == fakeMissingReaction {
+		return database.ErrCommentNotFound
+	}
+	return nil
+}
+
+func (db *fakeDatabase) AddToGroup(groupname string, _ []string, currentUser string) error {
+	return db.requireGroupAdmin(groupname, currentUser)
+}
+
+func (db *fakeDatabase) ChangeGroupPicture(currentUser string, groupName string, _ string) error {
+	return db.requireGroupAdmin(groupName, currentUser)
+}
+
+func (db *fakeDatabase) ChangeGroupName(currentUser string, oldGroupName string, _ string) error {
+	return db.requireGroupAdmin(oldGroupName, currentUser)
+}
+
+func (db *fakeDatabase) LeaveGroup(groupName string, currentUser string) error {
+	_, err := db.groupRole(groupName, currentUser)
+	return err
+}
+
+func (db *fakeDatabase) ListGroupMembers(groupname string) ([]database.GroupMember, error) {
+	var members []database.GroupMember
+	for username, role := range fakeGroupRoles {
+		members = append(members, database.GroupMember{Username: username, Role: role})
+	}
+	return members, nil
+}
+
+func (db *fakeDatabase) SetGroupRole(currentUser string, groupname string, _ string, _ string) error {
+	role, err := db.groupRole(groupname, currentUser)
+	if err != nil {
+		return err
+	}
+	if role != database.RoleOwner {
+		return database.ErrNotGroupOwner
+	}
+	return nil
+}
+
+func (db *fakeDatabase) RemoveFromGroup(currentUser string, groupname string, _ string) (int, error) {
+	return 0, db.requireGroupAdmin(groupname, currentUser)
+}
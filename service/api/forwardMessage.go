@@ -2,19 +2,35 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
+// maxForwardRecipients is the maximum number of recipients of a single forward request.
+const maxForwardRecipients = 20
+
 // Struct for the decoding the Request
 type ForwardMessageRequest struct {
-	RecipientUsername string `json:"recipientUsername"`
+	RecipientUsername  string   `json:"recipientUsername"`  // A single recipient, as sent by older clients
+	RecipientUsernames []string `json:"recipientUsernames"` // The users and groups to forward the message to
+}
+
+// ForwardMessageResponse lists the forwarded copies, one per recipient.
+type ForwardMessageResponse struct {
+	Forwarded []ForwardedMessage `json:"forwarded"`
 }
 
-// forwardMessage handles forwarding a message to a new recipient.
+// ForwardedMessage is the copy of a forwarded message sent to a recipient.
+type ForwardedMessage struct {
+	Recipient string                       `json:"recipient"` // User or group the copy was sent to
+	Message   *database.ConversationDetail `json:"message"`   // The copy
+}
+
+// forwardMessage handles forwarding a message to one or more recipients.
 //
 // Parameters:
 // - w: The HTTP response writer used to send responses to the client.
@@ -23,18 +39,21 @@ type ForwardMessageRequest struct {
 //
 // Behavior:
 // - Extracts the authenticated username from the request context.
-// - Retrieves the `partner-username` and `message-id` from the URL parameters.
-// - Converts `message-id` to an integer and validates it.
-// - Decodes the request body to get the new recipient's username.
-// - Fetches the original message from the database to ensure it exists and belongs to the user.
-// - Forwards the message to the specified new recipient.
+// - Retrieves the `partner-username` and `message-id` from the URL parameters: the message must be part of the
+// conversation of the user with the partner.
+// - Decodes the request body to get the recipients: `recipientUsernames`, and `recipientUsername` for older clients.
+// - Forwards the message to all the recipients at once, if the user is part of the conversation of the message. The
+// copies record the original message and sender.
 // - Responds with appropriate HTTP status codes and messages based on success or failure.
 //
 // Returns:
-// - 200 OK with the forwarded message details if the operation succeeds.
-// - 400 Bad Request if required parameters are missing or invalid.
+// - 200 OK with the forwarded copies if the operation succeeds.
+// - 400 Bad Request if required parameters are missing or invalid, or the message is a system message.
 // - 401 Unauthorized if the user is not authenticated.
-// - 404 Not Found if the original message does not exist.
+// - 403 Forbidden if the user is not a member of a recipient group.
+// - 404 Not Found if the original message does not exist, the user can't see it or it is not part of the conversation
+// with the partner, or a recipient does not exist.
+// - 409 Conflict if the message was deleted for everyone.
 // - 500 Internal Server Error if any database operation fails.
 func (rt *_router) forwardMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	// Set the response content type to JSON.
@@ -56,23 +75,16 @@ func (rt *_router) forwardMessage(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
-	// Extract the message ID from the URL parameters.
-	forwardedMessageIdStr := ps.ByName("message-id")
-	if forwardedMessageIdStr == "" {
-		// If the message ID is missing, respond with 400 Bad Request.
-		http.Error(w, `{"error": "message-id is required"}`, http.StatusBadRequest)
-		return
-	}
-
-	// Convert message-id from string to integer.
-	forwardedMessageId, err := strconv.Atoi(forwardedMessageIdStr)
-	if err != nil {
-		// If the conversion fails, respond with 400 Bad Request.
-		http.Error(w, `{"error": "invalid message-id format"}`, http.StatusBadRequest)
+	// The message, which the user can see (checked by wrapWithMessageAccess), must be part of the conversation with
+	// the partner.
+	forwardedMessageId := contextMessageID(r)
+	members := contextConversation(r)
+	if conversationNameFor(members, username) != partnerUsername {
+		http.Error(w, `{"error": "original message not found"}`, http.StatusNotFound)
 		return
 	}
 
-	// Decode the request body to extract the new recipient's username.
+	// Decode the request body to extract the recipients.
 	var request ForwardMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		// If JSON decoding fails, respond with 400 Bad Request.
@@ -80,42 +92,67 @@ func (rt *_router) forwardMessage(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
-	// Retrieve the original message to ensure it exists and belongs to the user.
-	originalMessage, err := rt.db.GetMessage(&forwardedMessageId)
-	if err != nil {
-		// If the original message is not found, respond with 404 Not Found.
-		http.Error(w, `{"error": "original message not found"}`, http.StatusNotFound)
+	// Collect the recipients, each one once.
+	var recipients []string
+	seen := make(map[string]bool)
+	for _, recipient := range append([]string{request.RecipientUsername}, request.RecipientUsernames...) {
+		if recipient != "" && !seen[recipient] {
+			seen[recipient] = true
+			recipients = append(recipients, recipient)
+		}
+	}
+	if len(recipients) == 0 {
+		http.Error(w, `{"error": "at least one recipient is required"}`, http.StatusBadRequest)
 		return
 	}
-
-	// Create the NewMessage struct to forward the message.
-	newMessage := database.NewMessage{
-		FromUser:    username,
-		ToUser:      request.RecipientUsername,
-		Content:     originalMessage.Content,
-		IsPhoto:     originalMessage.IsPhoto,
-		IsForwarded: true, // Mark it as forwarded
+	if len(recipients) > maxForwardRecipients {
+		http.Error(w, `{"error": "too many recipients"}`, http.StatusBadRequest)
+		return
 	}
 
-	// Forward the message to the new recipient.
-	messageID, err := rt.db.SendMessage(newMessage)
-	if err != nil {
+	// Forward the message, provided the user can see it.
+	messageIDs, err := rt.db.ForwardMessage(username, forwardedMessageId, recipients)
+	switch {
+	case errors.Is(err, database.ErrMessageNotFound):
+		http.Error(w, `{"error": "original message not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, database.ErrNotForwardable):
+		http.Error(w, `{"error": "`+database.ErrNotForwardable.Error()+`"}`, http.StatusBadRequest)
+		return
+	case errors.Is(err, database.ErrMessageDeleted):
+		http.Error(w, `{"error": "`+database.ErrMessageDeleted.Error()+`"}`, http.StatusConflict)
+		return
+	case errors.Is(err, database.ErrUserNotFound):
+		http.Error(w, `{"error": "recipient not found"}`, http.StatusNotFound)
+		return
+	case errors.Is(err, database.ErrNotGroupMember):
+		http.Error(w, `{"error": "user is not a member of a recipient group"}`, http.StatusForbidden)
+		return
+	case err != nil:
 		// If forwarding fails, respond with 500 Internal Server Error.
-		http.Error(w, `{"error": "failed to forward message: `+err.Error()+`"}`, http.StatusInternalServerError)
+		http.Error(w, `{"error": "failed to forward message"}`, http.StatusInternalServerError)
 		return
 	}
 
-	// Retrieve the newly forwarded message.
-	latestMessage, err := rt.db.GetMessage(&messageID)
-	if err != nil {
-		// If retrieving the forwarded message fails, respond with 500 Internal Server Error.
-		http.Error(w, `{"error": "failed to retrieve forwarded message"}`, http.StatusInternalServerError)
-		return
+	response := ForwardMessageResponse{Forwarded: make([]ForwardedMessage, 0, len(recipients))}
+	for i, recipient := range recipients {
+		// Notify the participants of the target conversation.
+		rt.notifyConversation(username, recipient, events.Event{Type: events.MessageSent, Actor: username, MessageID: messageIDs[i]})
+
+		// Retrieve the newly forwarded message.
+		copyMessage, err := rt.db.GetMessage(&messageIDs[i], username)
+		if err != nil {
+			// If retrieving the forwarded message fails, respond with 500 Internal Server Error.
+			http.Error(w, `{"error": "failed to retrieve forwarded message"}`, http.StatusInternalServerError)
+			return
+		}
+		rt.signPhoto(copyMessage)
+		response.Forwarded = append(response.Forwarded, ForwardedMessage{Recipient: recipient, Message: copyMessage})
 	}
 
-	// Send a 200 OK response with the forwarded message details.
+	// Send a 200 OK response with the forwarded copies.
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(latestMessage); err != nil {
+	if err := json.NewEncoder(w).Encode(response); err != nil {
 		// Handle any potential error during JSON encoding.
 		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
 		return
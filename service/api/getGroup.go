@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetGroupResponse represents the JSON response with the details of a group
+type GetGroupResponse struct {
+	Groupname     string                 `json:"groupname"`
+	GroupPhotoURL string                 `json:"group_photo_url"`
+	Members       []database.GroupMember `json:"members"`
+}
+
+// getGroup returns the details of a group, including its members and their roles. Only members can see them.
+//
+// Returns:
+// - 200 OK with the group details.
+// - 401 Unauthorized if the user is not authenticated.
+// - 403 Forbidden if the user is not a member of the group.
+// - 404 Not Found if the group does not exist.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) getGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract the username from the request context.
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// Load the group
+	group, err := rt.db.GetGroupByName(ps.ByName("groupname"))
+	if errors.Is(err, database.ErrGroupNotFound) {
+		http.Error(w, `{"error": "group not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"error": "failed to retrieve group"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Load its members, which also tells whether the user can see the group
+	members, err := rt.db.ListGroupMembers(group.Groupname)
+	if err != nil {
+		http.Error(w, `{"error": "failed to retrieve group members"}`, http.StatusInternalServerError)
+		return
+	}
+	isGroupMember := false
+	for _, member := range members {
+		if member.Username == username {
+			isGroupMember = true
+			break
+		}
+	}
+	if !isGroupMember {
+		http.Error(w, `{"error": "user is not a member of the group"}`, http.StatusForbidden)
+		return
+	}
+
+	// Send the response
+	response := GetGroupResponse{Groupname: group.Groupname, GroupPhotoURL: group.GroupPhotoUrl, Members: members}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		// If there was an error while encoding the response, return a 500 Internal Server Error
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// getMessageEdits returns the previous versions of a message, oldest first.
+//
+// Parameters:
+// - w: HTTP response writer
+// - r: HTTP request
+// - ps: Route parameters (contains message-id)
+//
+// Returns:
+// - 200 OK with the list of previous versions (empty if the message was never edited).
+// - 400 Bad Request if the message-id is invalid.
+// - 401 Unauthorized if the user is not authenticated.
+// - 404 Not Found if the message does not exist or the user does not take part in its conversation.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) getMessageEdits(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 1. Extract the authenticated username from the request context
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// 2. The message, which the user can see (checked by wrapWithMessageAccess)
+	messageID := contextMessageID(r)
+
+	// 3. Load the history
+	edits, err := rt.db.GetMessageEdits(messageID)
+	if err != nil {
+		http.Error(w, `{"error": "failed to retrieve message edits"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(edits); err != nil {
+		// Handle any potential error during JSON encoding.
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// getMessageReceipts returns, for each recipient of a message, whether and when it was received and read.
+//
+// Parameters:
+// - w: HTTP response writer
+// - r: HTTP request
+// - ps: Route parameters (contains message-id)
+//
+// Returns:
+// - 200 OK with the receipts, ordered by username.
+// - 400 Bad Request if the message-id is invalid.
+// - 401 Unauthorized if the user is not authenticated.
+// - 404 Not Found if the message does not exist, or the user is neither its sender nor takes part in its conversation.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) getMessageReceipts(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 1. Extract the authenticated username from the request context
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// 2. The message, which the user sent or can see (checked by wrapWithSenderMessageAccess)
+	messageID := contextMessageID(r)
+
+	// 3. Load the receipts
+	receipts, err := rt.db.GetMessageReceipts(messageID)
+	if err != nil {
+		http.Error(w, `{"error": "failed to retrieve message receipts"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(receipts); err != nil {
+		// Handle any potential error during JSON encoding.
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
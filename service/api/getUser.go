@@ -0,0 +1,36 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// getUser returns the public profile (username and profile photo) of a user, found by its exact username. Unlike the
+// user search, it also finds the caller.
+//
+// Returns:
+// - 200 OK with the user.
+// - 401 Unauthorized if the username is missing or invalid in the context.
+// - 404 Not Found if the user does not exist.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) getUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user, err := rt.db.GetUser(ps.ByName("username"))
+	if errors.Is(err, database.ErrUserNotFound) {
+		http.Error(w, `{"error": "user not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"error": "failed to load user"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+)
+
+// writeGroupError responds with the error returned by a group operation: 404 if the group or the targeted member does
+// not exist, 403 if the user lacks the role for the operation, and `fallbackStatus` for any other error.
+func writeGroupError(w http.ResponseWriter, err error, fallbackStatus int) {
+	status := fallbackStatus
+	switch {
+	case errors.Is(err, database.ErrGroupNotFound), errors.Is(err, database.ErrMemberNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, database.ErrNotGroupMember),
+		errors.Is(err, database.ErrNotGroupAdmin),
+		errors.Is(err, database.ErrNotGroupOwner),
+		errors.Is(err, database.ErrCannotRemoveOwner):
+		status = http.StatusForbidden
+	}
+	http.Error(w, `{"error": "`+err.Error()+`"}`, status)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -34,11 +35,20 @@ func (rt *_router) leaveGroup(w http.ResponseWriter, r *http.Request, ps httprou
 	// Call the database function to remove the user from the group
 	err := rt.db.LeaveGroup(groupName, username)
 	if err != nil {
-		// If the database function returns an error, respond with a 400 Bad Request error
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		// If the database function returns an error, respond with 404/403 or a 400 Bad Request error
+		writeGroupError(w, err, http.StatusBadRequest)
 		return
 	}
 
+	// Notify the remaining members and the user who left.
+	members, err := rt.db.GetConversationMembers(username, groupName)
+	if err == nil {
+		members.Usernames = append(members.Usernames, username)
+		rt.publish(members, events.Event{Type: events.GroupChanged, Actor: username})
+	} else {
+		rt.baseLogger.WithError(err).Warn("can't resolve conversation members for notification")
+	}
+
 	// Send a success response
 	response := LeaveGroupResponse{Message: "Successfully left the group"}
 	w.WriteHeader(http.StatusOK)
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -16,13 +17,16 @@ import (
 //
 // Behavior:
 // - Extracts the username from the request context (set by the authentication middleware).
-// - Loads the user's conversations from the database, including the latest message and metadata.
-// - Marks all messages in the conversations as received.
+// - Loads the user's conversations from the database, including the latest message and metadata. Archived
+// conversations are only included with the `include_archived=true` query parameter.
+// - Marks all messages in the conversations as received, unless the `peek=true` query parameter is given.
 // - Reloads the user's conversations after marking messages as received.
-// - Responds with a JSON payload containing the updated list of conversations in reverse chronological order.
+// - Responds with a JSON payload containing the updated list of conversations: the pinned ones first, in their pin
+// order, then the others in reverse chronological order.
 //
 // Returns:
 // - 200 OK and a JSON array of conversations if the operation succeeds.
+// - 400 Bad Request if a query parameter is invalid.
 // - 401 Unauthorized if the username is missing or invalid in the context.
 // - 500 Internal Server Error if there is a database error or if the conversations cannot be loaded.
 func (rt *_router) listConversations(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -34,33 +38,50 @@ func (rt *_router) listConversations(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
+	// With `peek`, the conversations are only listed: delivery is then acknowledged with markMessagesReceived.
+	peek, err := parsePeek(r)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	includeArchived, err := parseFlag(r, "include_archived")
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
 	// Load the user's conversations from the database to get the partner names.
-	conversations, err := rt.db.LoadUserConversations(username)
+	conversations, err := rt.db.LoadUserConversations(username, includeArchived)
 	if err != nil {
 		// If there is an error loading conversations, respond with 500 Internal Server Error.
 		http.Error(w, `{"error": "failed to load conversations: `+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
 
-	// Mark all messages in the retrieved conversations as received.
-	for _, conversation := range conversations {
-		// For each conversation, update the message status for the current user.
-		err := rt.db.MarkAllMessagesAsReceived(conversation.Name, username)
+	if !peek {
+		// Mark all messages in the retrieved conversations as received.
+		for _, conversation := range conversations {
+			// For each conversation, update the message status for the current user.
+			updated, err := rt.db.MarkMessagesAsReceived(username, conversation.Name, 0)
+			if err != nil {
+				// If there is an error updating message status, respond with 500 Internal Server Error.
+				http.Error(w, `{"error": "failed to update message status"}`, http.StatusInternalServerError)
+				return
+			}
+			if updated > 0 {
+				rt.notifyConversation(username, conversation.Name, events.Event{Type: events.MessagesReceived, Actor: username})
+			}
+		}
+
+		// Reload the user's conversations after marking messages as received, ensuring we send up-to-date data.
+		conversations, err = rt.db.LoadUserConversations(username, includeArchived)
 		if err != nil {
-			// If there is an error updating message status, respond with 500 Internal Server Error.
-			http.Error(w, `{"error": "failed to update message status"}`, http.StatusInternalServerError)
+			// If there is an error reloading conversations, respond with 500 Internal Server Error.
+			http.Error(w, `{"error": "failed to reload conversations: `+err.Error()+`"}`, http.StatusInternalServerError)
 			return
 		}
 	}
 
-	// Reload the user's conversations after marking messages as received, ensuring we send up-to-date data.
-	conversations, err = rt.db.LoadUserConversations(username)
-	if err != nil {
-		// If there is an error reloading conversations, respond with 500 Internal Server Error.
-		http.Error(w, `{"error": "failed to reload conversations: `+err.Error()+`"}`, http.StatusInternalServerError)
-		return
-	}
-
 	// Set the response content type to JSON.
 	w.Header().Set("Content-Type", "application/json")
 
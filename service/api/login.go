@@ -1,12 +1,15 @@
 package api
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
-	"math/rand"
+	"net"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/DavideStummSapienza/WASAText/service/database"
 	"github.com/julienschmidt/httprouter"
@@ -15,14 +18,19 @@ import (
 // Define a struct to parse the incoming JSON request body and outgoing response
 type LoginRequest struct {
 	Username string `json:"username"`
+	Password string `json:"password"` // Only used when passwords are required
 }
 
 type LoginResponse struct {
-	Identifier int `json:"identifier"`
+	Identifier string `json:"identifier"`
 }
 
 // doLogin handles user login or account creation.
-// If the user exists, their identifier is returned. Otherwise, a new user is created.
+// If the user does not exist, it is created. In both cases a new session is started, and its token is returned as
+// identifier. A user can have several sessions at the same time (e.g., on different devices).
+//
+// When passwords are required, a new user sets their password with the first login, and every following login must
+// present it. Users created before passwords were required can't log in until they set one with PUT /password.
 func (rt *_router) login(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	// Set Content-Type for the response
@@ -42,20 +50,22 @@ func (rt *_router) login(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		return
 	}
 
+	// Validate the password (length only), if passwords are required
+	if rt.requirePassword && (len(request.Password) < minPasswordLength || len(request.Password) > maxPasswordLength) {
+		http.Error(w, `{"error": "password must be between 8 and 128 characters"}`, http.StatusBadRequest)
+		return
+	}
+
 	// Check if the user already exists in the database
 	// Check if the username already exists using GetUser
-	existingUser, err := rt.db.GetUser(request.Username)
-	log.Print(err, existingUser)
+	_, err := rt.db.GetUser(request.Username)
 	if err == nil {
 
-		// If user exists, respond with their identifier
-		response := LoginResponse{Identifier: existingUser.AuthToken}
-		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			// Handle any potential error during JSON encoding.
-			http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		// If user exists, check their credentials and start a new session for them
+		if rt.requirePassword && !rt.authenticate(w, r, request.Username, request.Password) {
 			return
 		}
+		rt.startSession(w, request.Username)
 		return
 
 	} else if !errors.Is(err, database.ErrUserNotFound) {
@@ -77,15 +87,93 @@ func (rt *_router) login(w http.ResponseWriter, r *http.Request, ps httprouter.P
 		return
 	}
 
-	// If the user does not exist, create a new user and return the generated token
-	authToken := generateToken() // Generate a random auth token
-	err = rt.db.CreateUser(request.Username, "", authToken)
+	// If the user does not exist, create a new user and start their first session
+	passwordHash := ""
+	if rt.requirePassword {
+		passwordHash, err = hashPassword(request.Password)
+		if err != nil {
+			log.Printf("Password hashing error: %v", err)
+			http.Error(w, `{"error": "Couldnt create new user"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+	err = rt.db.CreateUser(request.Username, "", passwordHash)
 	if err != nil {
 		http.Error(w, `{"error": "Couldnt create new user"}`, http.StatusInternalServerError)
 		return
 	}
 
-	// Respond with the newly created user's auth token
+	rt.startSession(w, request.Username)
+}
+
+// authenticate checks the password of an existing user and writes the error response if the login is refused.
+// Failed attempts are rate limited per username and per client address.
+// Users without a password (created before passwords were required) are refused.
+func (rt *_router) authenticate(w http.ResponseWriter, r *http.Request, username string, password string) bool {
+	userKey := "user:" + username
+	clientKey := "addr:" + clientAddress(r)
+
+	if wait := rt.loginLimiter.retryAfter(userKey, clientKey); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		http.Error(w, `{"error": "too many failed login attempts, try again later"}`, http.StatusTooManyRequests)
+		return false
+	}
+
+	passwordHash, err := rt.db.GetPasswordHash(username)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, `{"error": "database error"}`, http.StatusInternalServerError)
+		return false
+	}
+
+	// Password-less account: anyone could claim it with this login, its user must set a password from a session
+	// started before passwords were required (see setPassword)
+	if passwordHash == "" {
+		http.Error(w, `{"error": "account has no password yet, set one from an existing session"}`, http.StatusForbidden)
+		return false
+	}
+
+	match, err := verifyPassword(password, passwordHash)
+	if err != nil {
+		log.Printf("Error verifying password of %s: %v", username, err)
+		http.Error(w, `{"error": "failed to verify password"}`, http.StatusInternalServerError)
+		return false
+	}
+	if !match {
+		rt.loginLimiter.fail(userKey, clientKey)
+		http.Error(w, `{"error": "invalid username or password"}`, http.StatusUnauthorized)
+		return false
+	}
+
+	rt.loginLimiter.reset(userKey)
+	return true
+}
+
+// clientAddress returns the IP address of the client of the request.
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// startSession creates a new session for the user and responds with its token.
+func (rt *_router) startSession(w http.ResponseWriter, username string) {
+	authToken, err := generateToken() // Generate a random auth token
+	if err != nil {
+		log.Printf("Token generation error: %v", err)
+		http.Error(w, `{"error": "failed to create session"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := rt.db.CreateSession(username, authToken, rt.sessionTTL); err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, `{"error": "failed to create session"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Respond with the session token
 	response := LoginResponse{Identifier: authToken}
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -95,9 +183,11 @@ func (rt *_router) login(w http.ResponseWriter, r *http.Request, ps httprouter.P
 	}
 }
 
-// generateToken generates a random integer token
-func generateToken() int {
-	rand.Seed(time.Now().UnixNano()) // Set the seed for random number generation using the current time
-	token := rand.Intn(1000000000)   // Generate a random integer token (between 0 and 999999999)
-	return token
+// generateToken generates a cryptographically random session token (256 bits, URL-safe base64)
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("reading random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
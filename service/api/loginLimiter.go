@@ -0,0 +1,85 @@
+package api
+
+import (
+	"sync"
+	"time"
+
+	"github.com/DavideStummSapienza/WASAText/service/globaltime"
+)
+
+// Failed login attempts allowed per key (username or client address) within loginFailureWindow
+const (
+	maxLoginFailures   = 5
+	loginFailureWindow = 15 * time.Minute
+)
+
+// loginLimiter counts failed login attempts, to slow down password guessing.
+type loginLimiter struct {
+	mu        sync.Mutex
+	failures  map[string][]time.Time
+	lastSweep time.Time // Last time the expired keys of all users were dropped
+}
+
+func newLoginLimiter() *loginLimiter {
+	return &loginLimiter{failures: make(map[string][]time.Time)}
+}
+
+// retryAfter returns how long the caller has to wait before trying again with any of the keys, 0 if it can try now.
+func (l *loginLimiter) retryAfter(keys ...string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := globaltime.Now()
+	var wait time.Duration
+	for _, key := range keys {
+		recent := l.prune(key, now)
+		if len(recent) >= maxLoginFailures {
+			if w := recent[0].Add(loginFailureWindow).Sub(now); w > wait {
+				wait = w
+			}
+		}
+	}
+	return wait
+}
+
+// fail records a failed attempt for each key.
+func (l *loginLimiter) fail(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := globaltime.Now()
+	for _, key := range keys {
+		l.failures[key] = append(l.prune(key, now), now)
+	}
+
+	// Keys are otherwise only pruned when they are seen again: sweep them all once per window, so that keys that
+	// never come back (e.g., sprayed usernames) don't stay in memory forever
+	if now.Sub(l.lastSweep) >= loginFailureWindow {
+		for key := range l.failures {
+			l.prune(key, now)
+		}
+		l.lastSweep = now
+	}
+}
+
+// reset forgets the failures of a key (e.g., after a successful login).
+func (l *loginLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, key)
+}
+
+// prune drops the failures of the key older than the window and returns the remaining ones. The caller must hold l.mu.
+func (l *loginLimiter) prune(key string, now time.Time) []time.Time {
+	recent := l.failures[key]
+	for len(recent) > 0 && now.Sub(recent[0]) >= loginFailureWindow {
+		recent = recent[1:]
+	}
+	if len(recent) == 0 {
+		delete(l.failures, key)
+		return nil
+	}
+	l.failures[key] = recent
+	return recent
+}
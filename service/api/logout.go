@@ -0,0 +1,50 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// LogoutResponse represents the response structure after a successful logout.
+type LogoutResponse struct {
+	Message string `json:"message"`
+}
+
+// logout ends the session used to authenticate the request. Other sessions of the user stay valid.
+//
+// Returns:
+// - 200 OK if the session was revoked.
+// - 401 Unauthorized if the token is missing or invalid.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) logout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// The middleware already validated the token, read it again to revoke it
+	token, ok := bearerToken(r)
+	if !ok {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	err := rt.db.DeleteSession(token)
+	if errors.Is(err, database.ErrSessionNotFound) {
+		// Revoked concurrently (e.g., double logout)
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, `{"error": "failed to end session"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := LogoutResponse{Message: "Logged out successfully"}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		// Handle any potential error during JSON encoding.
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
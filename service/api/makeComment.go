@@ -2,15 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
-	"strconv"
 
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
 // MakeCommentRequest represents the expected structure of the request body.
 type MakeCommentRequest struct {
-	Content string `json:"content"`
+	Content string `json:"content"` // A single emoji
 }
 
 // MakeCommentResponse represents the response structure after a successful comment creation.
@@ -18,7 +20,8 @@ type MakeCommentResponse struct {
 	Message string `json:"message"`
 }
 
-// makeComment handles adding a comment to a specific message.
+// makeComment handles adding a comment (an emoji reaction) to a specific message. A user can react to a message with
+// several emojis, each of them once: adding a reaction again has no effect.
 //
 // Parameters:
 // - w: HTTP response writer.
@@ -27,9 +30,11 @@ type MakeCommentResponse struct {
 //
 // Responses:
 // - 201 Created: The comment was successfully added.
-// - 400 Bad Request: The request is missing required parameters or has an invalid format.
+// - 400 Bad Request: The request is missing required parameters or has an invalid format, or the content is not a
+// single emoji.
 // - 401 Unauthorized: The user is not authenticated.
-// - 404 Not Found: The referenced message does not exist.
+// - 404 Not Found: The referenced message does not exist, or the user does not take part in its conversation.
+// - 409 Conflict: The message was deleted for everyone.
 // - 500 Internal Server Error: A database error occurred.
 func (rt *_router) makeComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
@@ -41,13 +46,8 @@ func (rt *_router) makeComment(w http.ResponseWriter, r *http.Request, ps httpro
 		return
 	}
 
-	// Extract and validate the message ID from the URL parameters.
-	messageIDStr := ps.ByName("message-id")
-	messageID, err := strconv.Atoi(messageIDStr)
-	if err != nil {
-		http.Error(w, `{"error": "invalid message-id format"}`, http.StatusBadRequest)
-		return
-	}
+	// The message, which the user can see (checked by wrapWithMessageAccess).
+	messageID := contextMessageID(r)
 
 	// Decode and validate the request body.
 	var req MakeCommentRequest
@@ -55,14 +55,24 @@ func (rt *_router) makeComment(w http.ResponseWriter, r *http.Request, ps httpro
 		http.Error(w, `{"error": "invalid or missing comment content"}`, http.StatusBadRequest)
 		return
 	}
+	if err := validateEmoji(req.Content); err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
 
 	// Insert the comment into the database.
-	err = rt.db.AddComment(messageID, username, req.Content)
-	if err != nil {
+	err := rt.db.AddComment(messageID, username, req.Content)
+	if errors.Is(err, database.ErrMessageDeleted) {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+		return
+	} else if err != nil {
 		http.Error(w, `{"error": "failed to add comment"}`, http.StatusInternalServerError)
 		return
 	}
 
+	// Notify the participants of the conversation.
+	rt.notifyMessage(messageID, events.Event{Type: events.CommentAdded, Actor: username, MessageID: messageID})
+
 	// Return success response.
 	response := MakeCommentResponse{Message: "Comment added successfully"}
 	w.WriteHeader(http.StatusCreated)
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
+	"github.com/julienschmidt/httprouter"
+)
+
+// MarkMessagesRequest represents the optional body of markMessagesReceived and markMessagesRead.
+type MarkMessagesRequest struct {
+	UpTo int `json:"up_to"` // Last message to mark (included), all messages if missing or 0
+}
+
+// MarkMessagesResponse represents the JSON response of markMessagesReceived and markMessagesRead.
+type MarkMessagesResponse struct {
+	Updated int `json:"updated"` // Number of messages newly marked
+}
+
+// markMessagesReceived acknowledges the delivery of the messages of a conversation, up to a given message. Clients
+// call it when they get messages through the event stream or by fetching with `peek=true`.
+//
+// Returns:
+// - 200 OK with the number of messages newly marked as received.
+// - 400 Bad Request if the body is invalid or `up_to` is not a message of the conversation.
+// - 401 Unauthorized if the user is not authenticated.
+// - 403 Forbidden if the partner is a group the user is not a member of.
+// - 404 Not Found if the conversation partner does not exist.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) markMessagesReceived(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rt.markMessages(w, r, ps, rt.db.MarkMessagesAsReceived, events.MessagesReceived)
+}
+
+// markMessagesRead marks the messages of a conversation as read, up to a given message. Clients call it when the
+// messages are actually shown to the user.
+//
+// Returns:
+// - 200 OK with the number of messages newly marked as read.
+// - 400 Bad Request if the body is invalid or `up_to` is not a message of the conversation.
+// - 401 Unauthorized if the user is not authenticated.
+// - 403 Forbidden if the partner is a group the user is not a member of.
+// - 404 Not Found if the conversation partner does not exist.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) markMessagesRead(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rt.markMessages(w, r, ps, rt.db.MarkMessagesAsRead, events.MessagesRead)
+}
+
+// markMessages updates the status of the messages of the conversation with `mark`, and notifies the participants
+// with an event of type `eventType` if any message was updated.
+func (rt *_router) markMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Params,
+	mark func(username, partnerUsername string, upTo int) (int, error), eventType string) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract the username from the request context.
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	partnerUsername := ps.ByName("partner-username")
+
+	// Parse the optional request body
+	var req MarkMessagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.UpTo < 0 {
+		http.Error(w, `{"error": "invalid up_to message id"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Update the statuses
+	updated, err := mark(username, partnerUsername, req.UpTo)
+	if errors.Is(err, database.ErrMessageNotInConversation) {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, `{"error": "failed to update message status"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Notify the senders
+	if updated > 0 {
+		rt.notifyConversation(username, partnerUsername, events.Event{Type: eventType, Actor: username, MessageID: req.UpTo})
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(MarkMessagesResponse{Updated: updated}); err != nil {
+		// Handle any potential error during JSON encoding.
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
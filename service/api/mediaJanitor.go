@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/DavideStummSapienza/WASAText/service/globaltime"
+	"github.com/sirupsen/logrus"
+)
+
+// mediaJanitorBatch is how many images the janitor removes per query
+const mediaJanitorBatch = 100
+
+// mediaDeleteTimeout is how long the janitor waits for the media storage to remove a file. It is well below
+// mediaRemovalTimeout, after which uploads take over the files the janitor was removing.
+const mediaDeleteTimeout = 10 * time.Second
+
+// mediaGCStats are the metrics of the media janitor, published in /debug/vars as "media_gc". They are shared by all the
+// routers of the process, since expvar names are global.
+var mediaGCStats = expvar.NewMap("media_gc")
+
+// mediaJanitor periodically removes the uploaded files that are not used anywhere since at least gracePeriod.
+type mediaJanitor struct {
+	rt          *_router
+	interval    time.Duration
+	gracePeriod time.Duration
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// startMediaJanitor starts the janitor in a new goroutine. It runs until close is called.
+func startMediaJanitor(rt *_router, interval, gracePeriod time.Duration) *mediaJanitor {
+	j := &mediaJanitor{
+		rt:          rt,
+		interval:    interval,
+		gracePeriod: gracePeriod,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go j.loop()
+	return j
+}
+
+// close stops the janitor and waits for the current run, if any, to end.
+func (j *mediaJanitor) close() {
+	j.stopOnce.Do(func() { close(j.stop) })
+	<-j.done
+}
+
+func (j *mediaJanitor) loop() {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.stop:
+			return
+		case <-ticker.C:
+			j.run()
+		}
+	}
+}
+
+// run removes the orphaned files, in batches, until there are none left or the janitor is stopped.
+func (j *mediaJanitor) run() {
+	logger := j.rt.baseLogger.WithField("component", "media-janitor")
+	mediaGCStats.Add("runs", 1)
+
+	var files, bytes int64
+	failed := make(map[string]bool) // Images that couldn't be removed, listed again by the next batches
+	for {
+		names, err := j.rt.db.ListOrphanedMedia(j.gracePeriod, mediaJanitorBatch)
+		if err != nil {
+			logger.WithError(err).Error("can't list orphaned media")
+			mediaGCStats.Add("errors", 1)
+			break
+		}
+
+		progress := false
+		for _, name := range names {
+			select {
+			case <-j.stop:
+				return
+			default:
+			}
+			if failed[name] {
+				continue
+			}
+			n, size, ok := j.collect(name)
+			if !ok {
+				failed[name] = true
+				continue
+			}
+			progress = true
+			files += n
+			bytes += size
+		}
+
+		// Stop at the last batch, or when a batch only holds failures, which would be listed again and again
+		if len(names) < mediaJanitorBatch || !progress {
+			break
+		}
+	}
+
+	last := new(expvar.String)
+	last.Set(globaltime.Now().UTC().Format(time.RFC3339))
+	mediaGCStats.Set("last_run", last)
+	if files > 0 {
+		logger.WithField("files", files).WithField("bytes", bytes).Info("removed orphaned media")
+	}
+}
+
+// collect removes an orphaned image and its variants, and returns how many files and bytes were removed. It returns
+// ok = false if the image couldn't be removed, in which case it is still listed as orphaned.
+//
+// The records stay, marked as being deleted, until the files are removed: an upload of the same image meanwhile waits
+// for the removal to end, and then stores the image again (see storeMedia).
+func (j *mediaJanitor) collect(name string) (files int64, bytes int64, ok bool) {
+	logger := j.rt.baseLogger.WithField("component", "media-janitor").WithField("media", name)
+
+	marked, err := j.rt.db.MarkOrphanedMedia(name, j.gracePeriod)
+	if err != nil {
+		logger.WithError(err).Error("can't mark orphaned media")
+		mediaGCStats.Add("errors", 1)
+		return 0, 0, false
+	}
+
+	// The variants come first, and the original is kept if one of them couldn't be removed, as they refer to it
+	ok = true
+	for _, m := range marked {
+		if m.VariantOf == "" && !ok {
+			j.unmark(logger, m.Name)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), mediaDeleteTimeout)
+		err = j.rt.media.Delete(ctx, m.Name)
+		cancel()
+		if err != nil {
+			// Keep the record, so that the next runs retry
+			logger.WithError(err).WithField("file", m.Name).Error("can't delete orphaned file")
+			mediaGCStats.Add("errors", 1)
+			j.unmark(logger, m.Name)
+			ok = false
+			continue
+		}
+		if err := j.rt.db.DeleteMarkedMedia(m.Name); err != nil {
+			// The record stays marked, until the next runs delete it or an upload stores the file again
+			logger.WithError(err).WithField("file", m.Name).Error("can't delete the record of an orphaned file")
+			mediaGCStats.Add("errors", 1)
+			ok = false
+		}
+		files++
+		bytes += m.Size
+	}
+
+	mediaGCStats.Add("files_deleted", files)
+	mediaGCStats.Add("bytes_reclaimed", bytes)
+	return files, bytes, ok
+}
+
+// unmark clears the deletion mark of a file that is kept.
+func (j *mediaJanitor) unmark(logger *logrus.Entry, name string) {
+	if err := j.rt.db.UnmarkMedia(name); err != nil {
+		logger.WithError(err).WithField("file", name).Error("can't unmark orphaned file")
+		mediaGCStats.Add("errors", 1)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/globaltime"
+	"github.com/DavideStummSapienza/WASAText/service/media"
+	"github.com/sirupsen/logrus"
+)
+
+// failingMediaDatabase lists a full batch of orphaned images whose records can never be deleted.
+type failingMediaDatabase struct {
+	fakeDatabase
+	lists int
+}
+
+func (db *failingMediaDatabase) ListOrphanedMedia(_ time.Duration, limit int) ([]string, error) {
+	db.lists++
+	names := make([]string, limit)
+	for i := range names {
+		names[i] = fmt.Sprintf("%064d.png", i)
+	}
+	return names, nil
+}
+
+func (db *failingMediaDatabase) MarkOrphanedMedia(string, time.Duration) ([]database.Media, error) {
+	return nil, errors.New("database is locked")
+}
+
+func TestMediaJanitorStopsOnFailures(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	db := &failingMediaDatabase{}
+	j := &mediaJanitor{
+		rt:          &_router{db: db, baseLogger: logger},
+		interval:    time.Hour,
+		gracePeriod: time.Hour,
+		stop:        make(chan struct{}),
+	}
+
+	done := make(chan struct{})
+	go func() {
+		j.run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		close(j.stop)
+		t.Fatal("the janitor kept listing the same failing batch")
+	}
+
+	if db.lists != 1 {
+		t.Errorf("listed %d batches, want 1", db.lists)
+	}
+}
+
+// removingMediaDatabase has a file that the media janitor started removing at `deletingAt`, and whose record is gone
+// after `removing` reads.
+type removingMediaDatabase struct {
+	fakeDatabase
+	deletingAt time.Time
+	removing   int
+	unmarked   bool
+	created    int
+}
+
+func (db *removingMediaDatabase) GetMedia(name string) (*database.Media, error) {
+	if db.removing == 0 || db.unmarked {
+		return nil, database.ErrMediaNotFound
+	}
+	db.removing--
+	return &database.Media{Name: name, DeletingAt: &db.deletingAt}, nil
+}
+
+func (db *removingMediaDatabase) UnmarkMedia(string) error {
+	db.unmarked = true
+	return nil
+}
+
+func (db *removingMediaDatabase) CreateMedia(database.Media) error {
+	db.created++
+	return nil
+}
+
+func TestStoreMediaAfterRemoval(t *testing.T) {
+	tests := []struct {
+		name         string
+		deletingAt   time.Time
+		wantUnmarked bool
+	}{
+		{"waits for the removal", globaltime.Now(), false},
+		{"takes an abandoned removal over", globaltime.Now().Add(-2 * mediaRemovalTimeout), true},
+	}
+	for _, tt := range tests {
+		storage, err := media.NewLocalStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		db := &removingMediaDatabase{deletingAt: tt.deletingAt, removing: 3}
+		rt := &_router{db: db, media: storage}
+		file := media.File{Name: media.Name(testPNG(t), ".png"), Data: testPNG(t), MimeType: "image/png"}
+
+		if err := rt.storeMedia(httptest.NewRequest("POST", "/upload", nil), "alice", file, ""); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if db.unmarked != tt.wantUnmarked {
+			t.Errorf("%s: unmarked = %v, want %v", tt.name, db.unmarked, tt.wantUnmarked)
+		}
+		if db.created != 1 {
+			t.Errorf("%s: recorded the file %d times, want 1", tt.name, db.created)
+		}
+		stored, err := storage.Open(context.Background(), file.Name)
+		if err != nil {
+			t.Errorf("%s: the file was not stored again: %v", tt.name, err)
+			continue
+		}
+		_ = stored.Close()
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
+)
+
+// notifyConversation publishes an event to every participant of the conversation `username` has with `partnerName`.
+// It must be called after the change has been committed. Failures are only logged, as the change itself succeeded.
+func (rt *_router) notifyConversation(username, partnerName string, ev events.Event) {
+	members, err := rt.db.GetConversationMembers(username, partnerName)
+	if err != nil {
+		rt.baseLogger.WithError(err).Warn("can't resolve conversation members for notification")
+		return
+	}
+	rt.publish(members, ev)
+}
+
+// notifyMessage publishes an event to every participant of the conversation the message belongs to.
+func (rt *_router) notifyMessage(messageID int, ev events.Event) {
+	members, err := rt.db.GetMessageConversationMembers(messageID)
+	if err != nil {
+		rt.baseLogger.WithError(err).Warn("can't resolve conversation members for notification")
+		return
+	}
+	rt.publish(members, ev)
+}
+
+// publish sends the event to every member, naming the conversation the way each member sees it.
+func (rt *_router) publish(members *database.ConversationMembers, ev events.Event) {
+	ev.IsGroup = members.Groupname != ""
+	for _, member := range members.Usernames {
+		ev.Conversation = conversationNameFor(members, member)
+		rt.hub.Publish(member, ev)
+	}
+}
+
+// conversationNameFor returns the name under which `username` lists the conversation: the group name, or the other
+// participant of a 1:1 conversation.
+func conversationNameFor(members *database.ConversationMembers, username string) string {
+	if members.Groupname != "" {
+		return members.Groupname
+	}
+	for _, member := range members.Usernames {
+		if member != username {
+			return member
+		}
+	}
+	return username
+}
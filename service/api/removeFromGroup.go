@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RemoveFromGroupResponse represents the JSON response
+type RemoveFromGroupResponse struct {
+	Message string `json:"message"`
+}
+
+// removeFromGroup removes a member from a group, leaving a system message in the group conversation. Admins can
+// remove members, only the owner can remove admins, and the owner can't be removed. Members remove themselves with
+// leaveGroup.
+//
+// Returns:
+// - 200 OK if the member was removed.
+// - 400 Bad Request if the user tries to remove themselves.
+// - 401 Unauthorized if the user is not authenticated.
+// - 403 Forbidden if the user is not allowed to remove the member.
+// - 404 Not Found if the group or the member does not exist.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) removeFromGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract the username from the request context.
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	groupName := ps.ByName("groupname")
+	member := ps.ByName("username")
+
+	// Remove the member in the database
+	messageID, err := rt.db.RemoveFromGroup(username, groupName, member)
+	if errors.Is(err, database.ErrRemoveSelf) {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		writeGroupError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// Notify the remaining members and the removed one.
+	members, err := rt.db.GetConversationMembers(username, groupName)
+	if err == nil {
+		members.Usernames = append(members.Usernames, member)
+		rt.publish(members, events.Event{Type: events.GroupChanged, Actor: username, MessageID: messageID})
+	} else {
+		rt.baseLogger.WithError(err).Warn("can't resolve conversation members for notification")
+	}
+
+	// Send success response
+	response := RemoveFromGroupResponse{Message: "Member successfully removed from the group"}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		// If there was an error while encoding the response, return a 500 Internal Server Error
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
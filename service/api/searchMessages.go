@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Page sizes for message search
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+// SearchMessagesResponse represents the JSON response of searchMessages.
+type SearchMessagesResponse struct {
+	Hits       []database.MessageSearchHit `json:"hits"`        // Messages found, best matches first
+	NextOffset *int                        `json:"next_offset"` // Offset of the following page, null on the last page
+}
+
+// searchMessages handles full-text searches in the messages of the conversations of the user.
+//
+// Behavior:
+// - Extracts the username from the request context (set by the authentication middleware).
+// - Reads the search text `q`, and the optional `limit` and `offset` of the page, from the query string.
+// - Responds with the matching messages, each with a highlighted snippet and the name of its conversation.
+//
+// Returns:
+// - 200 OK with a page of hits and the offset of the next page.
+// - 400 Bad Request if `q` is empty, or the limit or the offset are invalid.
+// - 401 Unauthorized if the username is missing or invalid in the context.
+// - 500 Internal Server Error if the search fails.
+// - 501 Not Implemented if the server was built without full-text search (see database.ErrSearchUnavailable).
+func (rt *_router) searchMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract the username from the request context.
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	// Parse the query parameters
+	query := r.URL.Query()
+	limit := defaultSearchLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			http.Error(w, `{"error": "limit must be between 1 and 50"}`, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, `{"error": "invalid offset"}`, http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	// Search the messages
+	hits, more, err := rt.db.SearchMessages(username, query.Get("q"), limit, offset)
+	if errors.Is(err, database.ErrEmptySearch) {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	} else if errors.Is(err, database.ErrSearchUnavailable) {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotImplemented)
+		return
+	} else if err != nil {
+		http.Error(w, `{"error": "failed to search messages"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := SearchMessagesResponse{Hits: hits}
+	if more {
+		next := offset + limit
+		response.NextOffset = &next
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+	}
+}
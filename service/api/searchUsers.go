@@ -2,27 +2,72 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/DavideStummSapienza/WASAText/service/database"
 	"github.com/julienschmidt/httprouter"
 )
 
-type SearchRequest struct {
-	Username string `json:"username"`
-}
+// Page sizes for user search
+const (
+	defaultUserSearchLimit = 20
+	maxUserSearchLimit     = 50
+)
 
 // searchUsers handles the search for users based on a partial or full username.
+//
+// Behavior:
+// - Reads the `username` to search for, and the optional `limit` and `offset` of the page, from the query string.
+// - Responds with the users whose username contains it, best matches first. The current user is not included.
+//
+// Returns:
+// - 200 OK with a page of users.
+// - 400 Bad Request if `username` is empty, or the limit or the offset are invalid.
+// - 401 Unauthorized if the username is missing or invalid in the context.
+// - 500 Internal Server Error if the search fails.
 func (rt *_router) searchUsers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	// Set Content-Type for the response
 	w.Header().Set("content-type", "application/json")
 
+	// Extract the username from the request context.
+	currentUser, ok := r.Context().Value(usernameKey).(string)
+	if !ok || currentUser == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
 	// Parse the query parameters from the URL
-	username := r.URL.Query().Get("username") // Get the 'name' query parameter
+	query := r.URL.Query()
+	username := query.Get("username")
+
+	limit := defaultUserSearchLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxUserSearchLimit {
+			http.Error(w, `{"error": "limit must be between 1 and 50"}`, http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, `{"error": "invalid offset"}`, http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
 
 	// Perform the search using the database
-	users, err := rt.db.SearchUser(username)
-	if err != nil {
+	users, err := rt.db.SearchUser(currentUser, username, limit, offset)
+	if errors.Is(err, database.ErrEmptyUserSearch) {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	} else if err != nil {
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
@@ -2,9 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
@@ -12,6 +14,7 @@ import (
 type sendMessageRequest struct {
 	Message string `json:"message"`  // The message content (text)
 	IsPhoto bool   `json:"is_photo"` // Whether the message is a photo message
+	ReplyTo int    `json:"reply_to"` // ID of the message this one replies to (optional)
 }
 
 // sendMessage handles the API request to send a message to a specific user or group.
@@ -25,12 +28,14 @@ type sendMessageRequest struct {
 // - Extracts the username from the request context (set by the authentication middleware).
 // - Retrieves the `partnerUsername` from the URL parameters.
 // - Sends a message to the specified partner and creates a new conversation if necessary.
+// - If `reply_to` is set, the message replies to that message, which must be in the same conversation.
 // - Responds with the details of the sent message or an error.
 //
 // Returns:
 // - 200 OK and the message details if the operation succeeds.
-// - 400 Bad Request if the `message` or `partner-username` is missing or invalid.
+// - 400 Bad Request if the `message` or `partner-username` is missing or invalid, or `reply_to` is not in the conversation.
 // - 401 Unauthorized if the username is missing or invalid in the context.
+// - 403 Forbidden if the user is not a member of the group.
 // - 404 Not Found if the conversation partner does not exist or is not a valid user.
 // - 500 Internal Server Error if there is a database error or if the message cannot be sent.
 
@@ -71,7 +76,7 @@ func (rt *_router) sendMessage(w http.ResponseWriter, r *http.Request, ps httpro
 
 	// Validate PhotoURL if IsPhoto is true
 	if request.IsPhoto {
-		if err := validateURL(request.Message); err != nil {
+		if err := rt.validateURL(request.Message); err != nil {
 			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
 			return
 		}
@@ -84,18 +89,31 @@ func (rt *_router) sendMessage(w http.ResponseWriter, r *http.Request, ps httpro
 		Content:     request.Message,
 		IsPhoto:     request.IsPhoto,
 		IsForwarded: false, // not forwarded by default
+		ReplyTo:     request.ReplyTo,
 	}
 
 	// Create the message in the database by calling SendMessage
 	messageID, err := rt.db.SendMessage(newMessage)
-	if err != nil {
+	if errors.Is(err, database.ErrInvalidReply) {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	} else if errors.Is(err, database.ErrUserNotFound) {
+		http.Error(w, `{"error": "conversation partner not found"}`, http.StatusNotFound)
+		return
+	} else if errors.Is(err, database.ErrNotGroupMember) {
+		http.Error(w, `{"error": "user is not a member of the group"}`, http.StatusForbidden)
+		return
+	} else if err != nil {
 		// If there is an error sending the message, respond with 500 Internal Server Error.
 		http.Error(w, `{"error": "failed to send message: `+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
 
+	// Notify the participants of the conversation.
+	rt.notifyConversation(username, partnerUsername, events.Event{Type: events.MessageSent, Actor: username, MessageID: messageID})
+
 	// Retrieve the conversation details including the message.
-	latestMessage, err := rt.db.GetMessage(&messageID)
+	latestMessage, err := rt.db.GetMessage(&messageID, username)
 	if err != nil {
 		// If there's an error fetching the conversation details, respond with 500 Internal Server Error.
 		http.Error(w, `{"error": "failed to retrieve conversation detail: `+err.Error()+`"}`, http.StatusInternalServerError)
@@ -103,6 +121,7 @@ func (rt *_router) sendMessage(w http.ResponseWriter, r *http.Request, ps httpro
 	}
 
 	// Respond with the sent message details.
+	rt.signPhoto(latestMessage)
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(latestMessage); err != nil {
 		// If there's an error encoding the response, respond with 500 Internal Server Error.
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/DavideStummSapienza/WASAText/service/globaltime"
+	"github.com/DavideStummSapienza/WASAText/service/media"
+	"github.com/julienschmidt/httprouter"
+)
+
+// serveMedia serves an uploaded file from the media storage.
+//
+// Profile and group pictures are public. Any other file (e.g., a photo sent in a chat) is only served with a valid
+// signature in the query string: the API returns signed URLs of the photos of a conversation to its participants
+// only (see signPhoto). No authentication header is needed, so that the URLs can be used directly in the web pages.
+//
+// Returns:
+// - 200 OK with the content of the file.
+// - 403 Forbidden if the signature is invalid or expired.
+// - 404 Not Found if the file does not exist, or is private and no signature is given.
+// - 500 Internal Server Error if the file can't be read.
+func (rt *_router) serveMedia(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	name := ps.ByName("name")
+
+	// Check the access to the file
+	query := r.URL.Query()
+	now := globaltime.Now()
+	cacheControl := "public, max-age=86400"
+	if rt.mediaSigner.Verify(name, query, now) {
+		expires, _ := strconv.ParseInt(query.Get("expires"), 10, 64)
+		cacheControl = "private, max-age=" + strconv.FormatInt(expires-now.Unix(), 10)
+	} else {
+		public, err := rt.db.IsPublicMedia(name)
+		if err != nil {
+			http.Error(w, "failed to read file", http.StatusInternalServerError)
+			return
+		}
+		if !public {
+			if query.Has("sig") {
+				http.Error(w, "invalid or expired signature", http.StatusForbidden)
+			} else {
+				http.NotFound(w, r)
+			}
+			return
+		}
+	}
+
+	file, err := rt.media.Open(r.Context(), name)
+	if errors.Is(err, media.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, "failed to read file", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	if mimeType := mime.TypeByExtension(filepath.Ext(name)); mimeType != "" {
+		w.Header().Set("Content-Type", mimeType)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", cacheControl)
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, file)
+}
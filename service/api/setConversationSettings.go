@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/globaltime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// ConversationSettingsRequest represents the expected structure of the request body. It replaces all the settings:
+// omitted fields take their default value (not archived, not muted, not pinned).
+type ConversationSettingsRequest struct {
+	Archived   bool       `json:"archived"`    // Whether to archive the conversation
+	MutedUntil *time.Time `json:"muted_until"` // Mute the conversation until this time, null to unmute it
+	PinOrder   *int       `json:"pin_order"`   // Pin the conversation at this position (lowest first), null to unpin it
+}
+
+// setConversationSettings handles updating the settings of a conversation for the authenticated user: archiving,
+// muting and pinning it. The settings only affect the user's own list of conversations.
+//
+// Returns:
+// - 200 OK with the new settings.
+// - 400 Bad Request if the body is invalid.
+// - 401 Unauthorized if the user is not authenticated.
+// - 403 Forbidden if the partner is a group the user is not a member of.
+// - 404 Not Found if the conversation partner does not exist, or there is no conversation with it yet.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) setConversationSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract the username from the request context.
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	partnerUsername := ps.ByName("partner-username")
+
+	// Decode and validate the request body
+	var req ConversationSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.PinOrder != nil && *req.PinOrder < 0 {
+		http.Error(w, `{"error": "invalid pin_order"}`, http.StatusBadRequest)
+		return
+	}
+
+	// A mute that has already expired is no mute
+	settings := database.ConversationSettings{Archived: req.Archived, MutedUntil: req.MutedUntil, PinOrder: req.PinOrder}
+	if settings.MutedUntil != nil && !settings.MutedUntil.After(globaltime.Now()) {
+		settings.MutedUntil = nil
+	}
+
+	// Save the settings
+	err := rt.db.SetConversationSettings(username, partnerUsername, settings)
+	if errors.Is(err, database.ErrConversationNotFound) {
+		http.Error(w, `{"error": "conversation not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"error": "failed to update conversation settings"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Return the new settings
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(settings); err != nil {
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SetGroupRoleRequest represents the expected JSON body
+type SetGroupRoleRequest struct {
+	Role string `json:"role"` // "owner", "admin" or "member"
+}
+
+// SetGroupRoleResponse represents the JSON response
+type SetGroupRoleResponse struct {
+	Message string `json:"message"`
+}
+
+// setGroupRole changes the role of a group member. Only the owner can change roles; making another member the owner
+// transfers the ownership, and the previous owner becomes an admin.
+//
+// Returns:
+// - 200 OK if the role was changed.
+// - 400 Bad Request if the role is invalid.
+// - 401 Unauthorized if the user is not authenticated.
+// - 403 Forbidden if the user is not the owner of the group.
+// - 404 Not Found if the group or the member does not exist.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) setGroupRole(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract the username from the request context.
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	groupName := ps.ByName("groupname")
+	member := ps.ByName("username")
+
+	// Parse the request body
+	var req SetGroupRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Change the role in the database
+	err := rt.db.SetGroupRole(username, groupName, member, req.Role)
+	if errors.Is(err, database.ErrInvalidRole) {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		writeGroupError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// Notify the group members.
+	rt.notifyConversation(username, groupName, events.Event{Type: events.GroupChanged, Actor: username})
+
+	// Send success response
+	response := SetGroupRoleResponse{Message: "Role successfully changed"}
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		// If there was an error while encoding the response, return a 500 Internal Server Error
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
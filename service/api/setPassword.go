@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// SetPasswordRequest defines the structure of the request payload for setting the password of the user.
+type SetPasswordRequest struct {
+	CurrentPassword string `json:"current_password"` // Only checked if the user already has a password
+	Password        string `json:"password"`         // The new password
+}
+
+// SetPasswordResponse defines the structure of the response payload for a successful password change.
+type SetPasswordResponse struct {
+	Message string `json:"message"`
+}
+
+// setPassword sets or changes the password of the user of the session.
+// A user without a password (created before passwords were required) sets it from a session, which proves who they
+// are, while the login refuses them. Changing an existing password also requires the current one, whose failed
+// attempts are rate limited like logins.
+//
+// Returns:
+// - 200 OK if the password was set.
+// - 400 Bad Request if the request body is invalid or the new password is too short or too long.
+// - 401 Unauthorized if the session is invalid or the current password is wrong.
+// - 429 Too Many Requests after repeated wrong current passwords.
+// - 500 Internal Server Error if the database operation fails.
+func (rt *_router) setPassword(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Extract the username from the request context
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	var request SetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, `{"error": "invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if len(request.Password) < minPasswordLength || len(request.Password) > maxPasswordLength {
+		http.Error(w, `{"error": "password must be between 8 and 128 characters"}`, http.StatusBadRequest)
+		return
+	}
+
+	// A password already set can only be changed by who knows it
+	passwordHash, err := rt.db.GetPasswordHash(username)
+	if err != nil {
+		log.Printf("Database error: %v", err)
+		http.Error(w, `{"error": "database error"}`, http.StatusInternalServerError)
+		return
+	}
+	if passwordHash != "" && !rt.authenticate(w, r, username, request.CurrentPassword) {
+		return
+	}
+
+	passwordHash, err = hashPassword(request.Password)
+	if err == nil {
+		err = rt.db.SetPasswordHash(username, passwordHash)
+	}
+	if err != nil {
+		log.Printf("Error setting password: %v", err)
+		http.Error(w, `{"error": "failed to set password"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(SetPasswordResponse{Message: "password successfully set"}); err != nil {
+		http.Error(w, `{"error": "failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
@@ -2,12 +2,22 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/events"
 	"github.com/julienschmidt/httprouter"
 )
 
+// Page sizes for conversation history
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
 // showConversation handles requests to fetch the details of a conversation.
 //
 // Parameters:
@@ -18,14 +28,16 @@ import (
 // Behavior:
 // - Extracts the username from the request context (set by the authentication middleware).
 // - Retrieves the `partnerUsername` from the route parameters.
-// - Marks all messages in the conversation as read for the user.
-// - Fetches the conversation details using the database function.
+// - Reads the optional `before`/`after` message-id cursors and the page `limit` from the query string.
+// - Marks all messages in the conversation as read for the user, unless the `peek=true` query parameter is given.
+// - Fetches the requested page of the conversation using the database function.
 // - Responds with appropriate HTTP status codes and messages for success or failure.
 //
 // Returns:
-// - 200 OK with the conversation details if the operation succeeds.
-// - 400 Bad Request if the `partnerUsername` is missing or invalid.
+// - 200 OK with the page of messages and the cursor of the next page if the operation succeeds.
+// - 400 Bad Request if the `partnerUsername`, a cursor or the limit is missing or invalid.
 // - 401 Unauthorized if the username is missing or invalid in the context.
+// - 403 Forbidden if the partner is a group the user is not a member of.
 // - 404 Not Found if the conversation partner does not exist.
 // - 500 Internal Server Error if there is a database error.
 func (rt *_router) showConversation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -48,35 +60,52 @@ func (rt *_router) showConversation(w http.ResponseWriter, r *http.Request, ps h
 		return
 	}
 
-	log.Printf("INFO: showConversation called for user: %s, partner: %s", username, partnerUsername)
-
-	// Mark all messages in the conversation as received.
-	err := rt.db.MarkAllMessagesAsReceived(username, partnerUsername)
+	// Parse the page to return.
+	page, err := parsePageRequest(r)
 	if err != nil {
-		log.Printf("ERROR: Failed to mark messages as received: %v", err)
-		http.Error(w, `{"error": "failed to update message status"}`, http.StatusInternalServerError)
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
 		return
 	}
 
-	// Mark all messages in the conversation as read.
-	err = rt.db.MarkAllMessagesAsRead(username, partnerUsername)
+	// With `peek`, the conversation is only fetched: it is marked as read with markMessagesRead.
+	peek, err := parsePeek(r)
 	if err != nil {
-		log.Printf("ERROR: Failed to mark messages as read: %v", err)
-		// If there is an error updating message status, respond with 500 Internal Server Error.
-		http.Error(w, `{"error": "failed to update message status "}`, http.StatusInternalServerError)
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
 		return
 	}
 
+	log.Printf("INFO: showConversation called for user: %s, partner: %s", username, partnerUsername)
+
+	if !peek {
+		// Mark all messages in the conversation as read (which also marks them as received).
+		updated, err := rt.db.MarkMessagesAsRead(username, partnerUsername, 0)
+		if err != nil {
+			log.Printf("ERROR: Failed to mark messages as read: %v", err)
+			// If there is an error updating message status, respond with 500 Internal Server Error.
+			http.Error(w, `{"error": "failed to update message status "}`, http.StatusInternalServerError)
+			return
+		}
+		if updated > 0 {
+			rt.notifyConversation(username, partnerUsername, events.Event{Type: events.MessagesRead, Actor: username})
+		}
+	}
+
 	// Fetch the conversation details from the database.
-	conversation, err := rt.db.ShowConversation(username, partnerUsername)
-	if err != nil {
+	conversation, err := rt.db.ShowConversation(username, partnerUsername, page)
+	if errors.Is(err, database.ErrInvalidCursor) {
+		http.Error(w, `{"error": "cursor is not a message of this conversation"}`, http.StatusBadRequest)
+		return
+	} else if err != nil {
 		log.Printf("ERROR: Failed to fetch conversation: %v", err)
 		// If there is an error fetching the conversation, respond with 500 Internal Server Error.
 		http.Error(w, `{"error": "failed to fetch conversation: `+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
 
-	// Send a 200 OK response with the conversation details.
+	// Send a 200 OK response with the conversation details, with URLs granting access to its photos.
+	for i := range conversation.Messages {
+		rt.signPhoto(&conversation.Messages[i])
+	}
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(conversation); err != nil {
 		log.Printf("ERROR: Failed to encode response: %v", err)
@@ -85,3 +114,57 @@ func (rt *_router) showConversation(w http.ResponseWriter, r *http.Request, ps h
 		return
 	}
 }
+
+// parsePageRequest reads the `before`, `after` and `limit` query parameters of a conversation request.
+func parsePageRequest(r *http.Request) (database.PageRequest, error) {
+	query := r.URL.Query()
+	page := database.PageRequest{Limit: defaultPageLimit}
+
+	if before := query.Get("before"); before != "" {
+		id, err := strconv.Atoi(before)
+		if err != nil || id <= 0 {
+			return page, errors.New("invalid before cursor")
+		}
+		page.Before = id
+	}
+
+	if after := query.Get("after"); after != "" {
+		id, err := strconv.Atoi(after)
+		if err != nil || id <= 0 {
+			return page, errors.New("invalid after cursor")
+		}
+		page.After = id
+	}
+
+	if page.Before != 0 && page.After != 0 {
+		return page, errors.New("before and after can not be used together")
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return page, errors.New("limit must be between 1 and 100")
+		}
+		page.Limit = n
+	}
+
+	return page, nil
+}
+
+// parsePeek reads the `peek` query parameter, which asks to fetch data without marking messages as received or read.
+func parsePeek(r *http.Request) (bool, error) {
+	return parseFlag(r, "peek")
+}
+
+// parseFlag reads a boolean query parameter, false if it is missing.
+func parseFlag(r *http.Request, name string) (bool, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("invalid " + name + " flag")
+	}
+	return flag, nil
+}
@@ -2,5 +2,9 @@ package api
 
 // Close should close everything opened in the lifecycle of the `_router`; for example, background goroutines.
 func (rt *_router) Close() error {
-	return nil
+	// Stop removing orphaned uploads
+	rt.mediaJanitor.close()
+
+	// Terminate the open event streams
+	return rt.hub.Close()
 }
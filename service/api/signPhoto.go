@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/url"
+	"path"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/globaltime"
+)
+
+// signPhoto replaces the URL of an uploaded photo sent in a message with a signed URL, which lets the participant the
+// message is returned to fetch the photo (see serveMedia). URLs of photos stored elsewhere are left as they are.
+//
+// The expiration time is rounded, so that the URL of a photo stays the same for a while and the photo can be cached
+// by the clients. A signed URL is valid for at least half of mediaURLTTL.
+func (rt *_router) signPhoto(msg *database.ConversationDetail) {
+	if !msg.IsPhoto {
+		return
+	}
+	photoURL, err := url.Parse(msg.Content)
+	if err != nil {
+		return
+	}
+	dir, name := path.Split(photoURL.Path)
+	if dir != "/uploads/" {
+		return
+	}
+
+	expires := globaltime.Now().Truncate(rt.mediaURLTTL / 2).Add(rt.mediaURLTTL)
+	photoURL.RawQuery = rt.mediaSigner.Sign(name, expires)
+	msg.Content = photoURL.String()
+}
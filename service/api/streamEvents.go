@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// heartbeatInterval is how often a comment line is sent on idle streams, so that proxies keep the connection open
+// and dead clients are detected.
+const heartbeatInterval = 25 * time.Second
+
+// streamWriteTimeout bounds every single write to the client.
+const streamWriteTimeout = 10 * time.Second
+
+// streamEvents opens a Server-Sent Events stream that pushes an event to the authenticated user every time one of
+// their conversations changes (new/deleted messages, comments, group changes).
+//
+// Parameters:
+// - w: The HTTP response writer used to send responses to the client.
+// - r: The HTTP request received from the client.
+// - ps: URL parameters extracted by the router.
+//
+// Behavior:
+// - Subscribes the user to the event hub.
+// - Takes over the connection, as the server-wide WriteTimeout would otherwise cut the stream after a few seconds.
+// - Writes each event as an SSE message whose name is the event type and whose data is the JSON encoded event.
+// - Ends the stream when the client disconnects or the server shuts down.
+//
+// Returns:
+// - 200 OK with a `text/event-stream` body.
+// - 401 Unauthorized if the username is missing or invalid in the context.
+// - 500 Internal Server Error if the connection can not be streamed.
+// - 503 Service Unavailable if the server is shutting down.
+func (rt *_router) streamEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	// Extract the username from the request context.
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error": "streaming not supported"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before taking over the connection, so that no event is lost.
+	sub, err := rt.hub.Subscribe(username)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		http.Error(w, `{"error": "server is shutting down"}`, http.StatusServiceUnavailable)
+		return
+	}
+	defer rt.hub.Unsubscribe(sub)
+
+	conn, bufrw, err := hijacker.Hijack()
+	if err != nil {
+		rt.baseLogger.WithError(err).Warn("can't take over the event stream connection")
+		return
+	}
+	defer conn.Close()
+
+	// Remove the deadlines set by the HTTP server for regular requests.
+	_ = conn.SetDeadline(time.Time{})
+
+	// Write the response head by hand, keeping the headers already set by the middlewares (e.g., CORS).
+	header := w.Header().Clone()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "close")
+	_, _ = bufrw.WriteString("HTTP/1.1 200 OK\r\n")
+	_ = header.Write(bufrw)
+	_, _ = bufrw.WriteString("\r\n")
+	_, _ = bufrw.WriteString("retry: 3000\n\n")
+	if err := flushStream(conn, bufrw); err != nil {
+		return
+	}
+
+	// The client is not expected to send anything: a read returning means that it went away.
+	gone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, bufrw)
+		close(gone)
+	}()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case ev, open := <-sub.C:
+			if !open {
+				// Unsubscribed by the hub: either shutdown, or the client was too slow
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				rt.baseLogger.WithError(err).Error("can't encode event")
+				continue
+			}
+			_, _ = fmt.Fprintf(bufrw, "event: %s\ndata: %s\n\n", ev.Type, data)
+		case <-heartbeat.C:
+			_, _ = bufrw.WriteString(": ping\n\n")
+		case <-gone:
+			return
+		}
+
+		if err := flushStream(conn, bufrw); err != nil {
+			return
+		}
+	}
+}
+
+// flushStream sends buffered stream data to the client, giving up if the client does not read it in time.
+func flushStream(conn net.Conn, bufrw *bufio.ReadWriter) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return bufrw.Flush()
+}
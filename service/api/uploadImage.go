@@ -1,91 +1,130 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"time"
 
+	"github.com/DavideStummSapienza/WASAText/service/database"
+	"github.com/DavideStummSapienza/WASAText/service/globaltime"
+	"github.com/DavideStummSapienza/WASAText/service/media"
 	"github.com/julienschmidt/httprouter"
 )
 
+// maxUploadSize is the maximum size of an uploaded image: 10MB
+const maxUploadSize = 10 << 20
+
+// An upload of a file that the media janitor is removing checks every mediaRemovalPoll whether the removal ended, for
+// at most mediaRemovalWait. A removal that started more than mediaRemovalTimeout ago was abandoned by a janitor that
+// stopped, and the upload takes the file over.
+const (
+	mediaRemovalPoll    = 50 * time.Millisecond
+	mediaRemovalWait    = 2 * time.Second
+	mediaRemovalTimeout = time.Minute
+)
+
+// errMediaBeingRemoved is returned by storeMedia if the media janitor didn't finish removing the file in time.
+var errMediaBeingRemoved = errors.New("the file is being removed, try again")
+
 // Response structure for image upload
 type UploadImageResponse struct {
-	ImageURL string `json:"imageUrl"`
+	ImageURL   string `json:"imageUrl"`   // The image, to send in messages
+	PreviewURL string `json:"previewUrl"` // A smaller version of the image, for chat previews
+	AvatarURL  string `json:"avatarUrl"`  // A square thumbnail of the image, for profile and group pictures
 }
 
-// uploadImage handles the image upload request
+// uploadImage handles the image upload request.
+//
+// Behavior:
+// - Reads the image from the `image` field of the multipart form.
+// - Checks the type of the image from its content, decodes it and re-encodes it without metadata, and generates its
+// preview and avatar variants (see media.ProcessImage).
+// - Stores the files in the media storage under names made of the hash of their content, unless the same content was
+// already uploaded, and records them in the database.
+// - Responds with the URLs of the image and of its variants.
+//
+// Returns:
+// - 200 OK with the URLs of the image and of its variants.
+// - 400 Bad Request if the file is missing, too large, or not a valid JPEG, PNG or GIF image.
+// - 401 Unauthorized if the username is missing or invalid in the context.
+// - 503 Service Unavailable if the same image is being removed by the media janitor, see the Retry-After header.
+// - 500 Internal Server Error if the image can't be stored.
 func (rt *_router) uploadImage(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Max image size: 10MB
+	// Extract the username from the request context.
+	username, ok := r.Context().Value(usernameKey).(string)
+	if !ok || username == "" {
+		http.Error(w, `{"error": "unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
 	// Parse the form data, including file
-	err := r.ParseMultipartForm(10 << 20) // 10MB limit
+	err := r.ParseMultipartForm(maxUploadSize)
 	if err != nil {
 		http.Error(w, `{"error": "The uploaded file is too large or invalid."}`, http.StatusBadRequest)
 		return
 	}
 
 	// Get the file from the form data
-	file, handler, err := r.FormFile("image")
+	file, _, err := r.FormFile("image")
 	if err != nil {
 		http.Error(w, `{"error": "Unable to retrieve file from form."}`, http.StatusBadRequest)
 		return
 	}
 	defer file.Close()
 
-	// Check if the file extension is valid
-	allowedExtensions := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".gif": true}
-	ext := filepath.Ext(handler.Filename)
-
-	if !allowedExtensions[ext] {
-		http.Error(w, `{"error": "Invalid file type. Allowed types are: .jpg, .jpeg, .png, .gif."}`, http.StatusBadRequest)
+	// Read the whole file, to check its content
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadSize+1))
+	if err != nil {
+		http.Error(w, `{"error": "Failed to read the uploaded file."}`, http.StatusBadRequest)
+		return
+	} else if len(data) > maxUploadSize {
+		http.Error(w, `{"error": "The uploaded file is too large or invalid."}`, http.StatusBadRequest)
 		return
 	}
 
-	// Declare the directory where the images will be stored
-	uploadDir := "uploads"
-
-	// Check if the directory exists and handle any errors
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-
-		// Create the uploads directory if it doesn't exist
-		err := os.Mkdir(uploadDir, os.ModePerm)
-		if err != nil {
-			http.Error(w, `{"error": "Failed to create the uploads directory."}`, http.StatusInternalServerError)
-			return
-		}
+	// Validate and re-encode the image, and generate its variants
+	img, err := media.ProcessImage(data)
+	if errors.Is(err, media.ErrUnsupportedImage) || errors.Is(err, media.ErrInvalidImage) || errors.Is(err, media.ErrImageTooLarge) {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
 	} else if err != nil {
-
-		// If the error is not because the directory doesn't exist, log the error
-		http.Error(w, `{"error": "Failed to check the uploads directory."}`, http.StatusInternalServerError)
+		http.Error(w, `{"error": "Failed to process the image."}`, http.StatusInternalServerError)
 		return
 	}
 
-	// Create a unique filename for the uploaded image
-	newFileName := fmt.Sprintf("%d%s", os.Getpid(), ext)
-	filePath := filepath.Join(uploadDir, newFileName)
-
-	// Save the uploaded file on the server
-	dst, err := os.Create(filePath)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to save the file on the server."}`, http.StatusInternalServerError)
-		return
+	// Store the files, unless the same content is already stored. The original is recorded first, as the variants
+	// refer to it.
+	files := []struct {
+		file      media.File
+		variantOf string
+	}{
+		{img.Original, ""},
+		{img.Preview, img.Original.Name},
+		{img.Avatar, img.Original.Name},
 	}
-	defer dst.Close()
-
-	// Copy the uploaded file to the server
-	_, err = io.Copy(dst, file)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to write the file to the server."}`, http.StatusInternalServerError)
-		return
+	for _, f := range files {
+		if err := rt.storeMedia(r, username, f.file, f.variantOf); errors.Is(err, errMediaBeingRemoved) {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusServiceUnavailable)
+			return
+		} else if err != nil {
+			http.Error(w, `{"error": "Failed to save the file on the server."}`, http.StatusInternalServerError)
+			return
+		}
 	}
 
-	// Return the file path as part of the response
-	imageURL := fmt.Sprintf("http://%s/%s", r.Host, filePath)
-	response := UploadImageResponse{ImageURL: imageURL}
+	// Return the URLs of the files as part of the response
+	response := UploadImageResponse{
+		ImageURL:   mediaURL(r, img.Original.Name),
+		PreviewURL: mediaURL(r, img.Preview.Name),
+		AvatarURL:  mediaURL(r, img.Avatar.Name),
+	}
 
 	// Send the success response
 	w.WriteHeader(http.StatusOK)
@@ -95,3 +134,73 @@ func (rt *_router) uploadImage(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 }
+
+// storeMedia stores a file uploaded by `username` and records it. A file with the same name that was already stored is
+// not stored again, but its record is refreshed, so that the media janitor doesn't remove it before it is used. If the
+// janitor is already removing it, storeMedia waits for the removal to end, and stores the file again.
+func (rt *_router) storeMedia(r *http.Request, username string, file media.File, variantOf string) error {
+	record := database.Media{
+		Name:      file.Name,
+		Owner:     username,
+		Size:      int64(len(file.Data)),
+		MimeType:  file.MimeType,
+		VariantOf: variantOf,
+	}
+
+	m, err := rt.db.GetMedia(file.Name)
+	if err == nil && m.DeletingAt == nil {
+		// The refresh doesn't stop a removal that started since the record was read, so check again after it
+		if err := rt.db.CreateMedia(record); err != nil {
+			return err
+		}
+		m, err = rt.db.GetMedia(file.Name)
+		if err == nil && m.DeletingAt == nil {
+			return nil
+		}
+	}
+	if err == nil {
+		err = rt.waitMediaRemoval(r.Context(), file.Name)
+	}
+	if err != nil && !errors.Is(err, database.ErrMediaNotFound) {
+		return err
+	}
+
+	if err := rt.media.Put(r.Context(), file.Name, file.Data, file.MimeType); err != nil {
+		return err
+	}
+	return rt.db.CreateMedia(record)
+}
+
+// waitMediaRemoval waits until the media janitor no longer removes a file, which may then be gone from the storage.
+// It returns errMediaBeingRemoved if the removal doesn't end within mediaRemovalWait.
+func (rt *_router) waitMediaRemoval(ctx context.Context, name string) error {
+	deadline := time.NewTimer(mediaRemovalWait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(mediaRemovalPoll)
+	defer ticker.Stop()
+
+	for {
+		m, err := rt.db.GetMedia(name)
+		if err != nil || m.DeletingAt == nil {
+			// Removed, or kept since the janitor couldn't remove it
+			return err
+		}
+		if globaltime.Since(*m.DeletingAt) > mediaRemovalTimeout {
+			// Abandoned by a janitor that stopped while removing the file
+			return rt.db.UnmarkMedia(name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return errMediaBeingRemoved
+		case <-ticker.C:
+		}
+	}
+}
+
+// mediaURL returns the URL of an uploaded file.
+func mediaURL(r *http.Request, name string) string {
+	return fmt.Sprintf("http://%s/uploads/%s", r.Host, name)
+}
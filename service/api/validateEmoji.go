@@ -0,0 +1,178 @@
+package api
+
+import (
+	"errors"
+	"unicode"
+	"unicode/utf8"
+)
+
+// errNotEmoji is returned by validateEmoji.
+var errNotEmoji = errors.New("reaction must be a single emoji")
+
+// maxEmojiLength is the maximum length of a reaction in bytes, enough for the longest emoji ZWJ sequences (e.g.,
+// families with skin tones).
+const maxEmojiLength = 64
+
+// Code points that combine with pictographs into a single emoji, see Unicode Technical Standard #51.
+const (
+	zeroWidthJoiner     = '\u200D'
+	variationSelector16 = '\uFE0F'
+	combiningKeycap     = '\u20E3'
+	cancelTag           = '\U000E007F'
+)
+
+// emojiPictographic approximates the Extended_Pictographic property of Unicode, which the unicode package doesn't
+// provide: the code points that are displayed as emojis, alone or in sequences. Regional indicators and skin tone
+// modifiers are not included, as they are only valid in sequences.
+var emojiPictographic = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x00A9, 0x00A9, 1},
+		{0x00AE, 0x00AE, 1},
+		{0x203C, 0x203C, 1},
+		{0x2049, 0x2049, 1},
+		{0x2122, 0x2122, 1},
+		{0x2139, 0x2139, 1},
+		{0x2194, 0x2199, 1},
+		{0x21A9, 0x21AA, 1},
+		{0x231A, 0x231B, 1},
+		{0x2328, 0x2328, 1},
+		{0x2388, 0x2388, 1},
+		{0x23CF, 0x23CF, 1},
+		{0x23E9, 0x23F3, 1},
+		{0x23F8, 0x23FA, 1},
+		{0x24C2, 0x24C2, 1},
+		{0x25AA, 0x25AB, 1},
+		{0x25B6, 0x25B6, 1},
+		{0x25C0, 0x25C0, 1},
+		{0x25FB, 0x25FE, 1},
+		{0x2600, 0x27BF, 1},
+		{0x2934, 0x2935, 1},
+		{0x2B05, 0x2B07, 1},
+		{0x2B1B, 0x2B1C, 1},
+		{0x2B50, 0x2B50, 1},
+		{0x2B55, 0x2B55, 1},
+		{0x3030, 0x3030, 1},
+		{0x303D, 0x303D, 1},
+		{0x3297, 0x3297, 1},
+		{0x3299, 0x3299, 1},
+	},
+	R32: []unicode.Range32{
+		{0x1F000, 0x1F0FF, 1},
+		{0x1F10D, 0x1F10F, 1},
+		{0x1F12F, 0x1F12F, 1},
+		{0x1F16C, 0x1F171, 1},
+		{0x1F17E, 0x1F17F, 1},
+		{0x1F18E, 0x1F18E, 1},
+		{0x1F191, 0x1F19A, 1},
+		{0x1F1AD, 0x1F1E5, 1},
+		{0x1F201, 0x1F20F, 1},
+		{0x1F21A, 0x1F21A, 1},
+		{0x1F22F, 0x1F22F, 1},
+		{0x1F232, 0x1F23A, 1},
+		{0x1F23C, 0x1F23F, 1},
+		{0x1F249, 0x1F3FA, 1},
+		{0x1F400, 0x1F53D, 1},
+		{0x1F546, 0x1F64F, 1},
+		{0x1F680, 0x1F6FF, 1},
+		{0x1F774, 0x1F77F, 1},
+		{0x1F7D5, 0x1F7FF, 1},
+		{0x1F80C, 0x1F80F, 1},
+		{0x1F848, 0x1F84F, 1},
+		{0x1F85A, 0x1F85F, 1},
+		{0x1F888, 0x1F88F, 1},
+		{0x1F8AE, 0x1F8FF, 1},
+		{0x1F90C, 0x1F93A, 1},
+		{0x1F93C, 0x1F945, 1},
+		{0x1F947, 0x1FAFF, 1},
+		{0x1FC00, 0x1FFFD, 1},
+	},
+	LatinOffset: 2,
+}
+
+// validateEmoji validates that the content of a reaction is a single emoji: a pictograph, a flag or a keycap, or a
+// sequence of them joined with zero width joiners, which is displayed as a single character (e.g., woman + laptop
+// for the woman technologist).
+func validateEmoji(content string) error {
+	if content == "" || len(content) > maxEmojiLength || !utf8.ValidString(content) {
+		return errNotEmoji
+	}
+
+	runes := []rune(content)
+	i := 0
+	for {
+		n := emojiElementLength(runes[i:])
+		if n == 0 {
+			return errNotEmoji
+		}
+		i += n
+		if i == len(runes) {
+			return nil
+		}
+		if runes[i] != zeroWidthJoiner {
+			return errNotEmoji
+		}
+		i++ // Skip the joiner
+	}
+}
+
+// emojiElementLength returns the number of code points of the emoji at the beginning of `runes`, without joiners, or 0
+// if it doesn't start with an emoji.
+func emojiElementLength(runes []rune) int {
+	if len(runes) == 0 {
+		return 0
+	}
+
+	switch r := runes[0]; {
+	case isRegionalIndicator(r):
+		// A flag is a pair of regional indicators
+		if len(runes) >= 2 && isRegionalIndicator(runes[1]) {
+			return 2
+		}
+		return 0
+
+	case r == '#' || r == '*' || (r >= '0' && r <= '9'):
+		// A keycap, e.g. digit + keycap
+		n := 1
+		if n < len(runes) && runes[n] == variationSelector16 {
+			n++
+		}
+		if n < len(runes) && runes[n] == combiningKeycap {
+			return n + 1
+		}
+		return 0
+
+	case unicode.Is(emojiPictographic, r):
+		// A pictograph, optionally in emoji presentation or with a skin tone
+		n := 1
+		if n < len(runes) && (runes[n] == variationSelector16 || isEmojiModifier(runes[n])) {
+			n++
+		}
+
+		// Subdivision flags (e.g. the flag of Scotland) are followed by tags, ended by a cancel tag
+		if n < len(runes) && isTag(runes[n]) {
+			start := n
+			for n < len(runes) && isTag(runes[n]) && runes[n] != cancelTag {
+				n++
+			}
+			if n == start || n == len(runes) || runes[n] != cancelTag {
+				return 0
+			}
+			n++
+		}
+		return n
+	}
+
+	return 0
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isEmojiModifier(r rune) bool {
+	return r >= 0x1F3FB && r <= 0x1F3FF
+}
+
+func isTag(r rune) bool {
+	return r >= 0xE0020 && r <= cancelTag
+}
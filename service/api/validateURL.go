@@ -3,11 +3,18 @@ package api
 import (
 	"errors"
 	"net/url"
+	"path"
 	"strings"
+
+	"github.com/DavideStummSapienza/WASAText/service/database"
 )
 
-// validateURL validates that the given string is a properly formatted URL with http or https schemes.
-func validateURL(photoURL string) error {
+// validateURL validates that the given string is a properly formatted URL with http or https schemes, pointing to an
+// image.
+//
+// URLs of files uploaded to this server (with a `/uploads/<name>` path) must point to an uploaded image, which has
+// been checked by its content on upload. Other URLs must have the extension of an image file.
+func (rt *_router) validateURL(photoURL string) error {
 	// Parse the URL
 	parsedURL, err := url.ParseRequestURI(photoURL)
 	if err != nil {
@@ -19,6 +26,25 @@ func validateURL(photoURL string) error {
 		return errors.New("URL must use http or https")
 	}
 
+	// Check that an uploaded file is an image
+	if dir, name := path.Split(parsedURL.Path); dir == "/uploads/" {
+		// Signed URLs are only valid for a while: the URL of the file itself must be used
+		if parsedURL.RawQuery != "" {
+			return errors.New("URL of an uploaded image must not have a query")
+		}
+		m, err := rt.db.GetMedia(name)
+		if errors.Is(err, database.ErrMediaNotFound) {
+			return errors.New("URL must point to an uploaded image")
+		} else if err != nil {
+			return errors.New("failed to check the uploaded image")
+		}
+		// A file being removed by the media janitor may already be gone
+		if !strings.HasPrefix(m.MimeType, "image/") || m.DeletingAt != nil {
+			return errors.New("URL must point to an uploaded image")
+		}
+		return nil
+	}
+
 	// Check if the URL likely points to an image
 	if !strings.HasSuffix(strings.ToLower(parsedURL.Path), ".jpg") &&
 		!strings.HasSuffix(strings.ToLower(parsedURL.Path), ".jpeg") &&
@@ -6,25 +6,41 @@ import (
 )
 
 // AddComment inserts a comment into the `comments` table for a given message.
+// A user can react to a message with several emojis: adding a reaction the user already made has no effect.
 //
 // Parameters:
 // - messageID: The ID of the message to which the comment is added.
 // - currentUser: The username of the user who is making the comment.
-// - content: The comment text or emoji.
+// - content: The emoji of the reaction.
 //
 // Returns:
-// - error: An error if the database insertion fails.
+// - error: ErrMessageDeleted if the message was deleted for everyone, or an error if the database insertion fails.
 func (db *appdbimpl) AddComment(messageID int, currentUser string, content string) error {
-	_, err := db.c.Exec(`
+	res, err := db.c.Exec(`
         INSERT INTO comments (reactor_username, message_id, content)
-        VALUES (?, ?, ?)
-        ON CONFLICT(reactor_username, message_id) 
-        DO UPDATE SET content = ?`, currentUser, messageID, content, content)
+        SELECT ?, id, ? FROM messages WHERE id = ? AND deleted_at IS NULL
+        ON CONFLICT(reactor_username, message_id, content) DO NOTHING`, currentUser, content, messageID)
 
 	if err != nil {
 		log.Printf("failed to insert comment: %v", err)
 		return fmt.Errorf("failed to insert comment: %w", err)
 	}
 
+	// Nothing is inserted for a reaction already made, and for a deleted message
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		var deleted bool
+		err = db.c.QueryRow(`SELECT COUNT(*) > 0 FROM messages WHERE id = ? AND deleted_at IS NOT NULL`, messageID).Scan(&deleted)
+		if err != nil {
+			return fmt.Errorf("failed to find message: %w", err)
+		}
+		if deleted {
+			return ErrMessageDeleted
+		}
+	}
+
 	return nil
 }
@@ -8,6 +8,8 @@ import (
 
 // AddToGroup adds multiple users to a group.
 // If the group does not exist, it will be created, and the provided users (including the current user) will be added to the group.
+// The creator of a group becomes its owner; only the owner and the admins can add users to an existing group.
+// The creation and each new member are recorded with system messages in the group conversation.
 //
 // Parameters:
 // - groupname: The name of the group to which users should be added or created.
@@ -16,6 +18,7 @@ import (
 //
 // Returns:
 // - error: If an error occurs during the process, such as a database failure or permission issue, an error is returned.
+// ErrNotGroupMember or ErrNotGroupAdmin are returned if the current user can't add users to an existing group.
 func (db *appdbimpl) AddToGroup(groupname string, usernames []string, currentUser string) error {
 
 	// Check if the groupname already exists as a username
@@ -26,24 +29,30 @@ func (db *appdbimpl) AddToGroup(groupname string, usernames []string, currentUse
 		return fmt.Errorf("database error while checking username: %w", err)
 	}
 
-	// Check if the group exists
-	var groupCount int
-	err = db.c.QueryRow(`
-		SELECT COUNT(*) 
-		FROM groups 
-		WHERE groupname = ?;
-	`, groupname).Scan(&groupCount)
-
+	tx, err := db.c.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to check if group exists: %w", err)
+		return fmt.Errorf("failed to start transaction: %w", err)
 	}
 
-	// If the group doesn't exist, create it
-	if groupCount == 0 {
+	// Defer the rollback in case of any error, to ensure a clean up if something goes wrong
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
+
+	// Check if the group exists and whether the current user can add members to it
+	err = requireGroupAdmin(tx, groupname, currentUser)
 
+	switch {
+	case errors.Is(err, ErrGroupNotFound):
+		// If the group doesn't exist, create it
 		log.Print("Group created")
 
-		_, err := db.c.Exec(`
+		_, err = tx.Exec(`
 			INSERT INTO groups (groupname)
 			VALUES (?);
 		`, groupname)
@@ -53,8 +62,7 @@ func (db *appdbimpl) AddToGroup(groupname string, usernames []string, currentUse
 		}
 
 		// Create the conversation for the new group
-
-		_, err = db.c.Exec(`
+		_, err = tx.Exec(`
 			INSERT INTO conversations (groupname)
 			VALUES (?);
 		`, groupname)
@@ -65,24 +73,31 @@ func (db *appdbimpl) AddToGroup(groupname string, usernames []string, currentUse
 
 		log.Print("Conversation for group created")
 
-		// Add the current user to the group
-		_, err = db.c.Exec(`
-			INSERT INTO group_members (groupname, membername)
-			VALUES (?, ?);
-		`, groupname, currentUser)
+		// Add the current user to the group as its owner
+		_, err = tx.Exec(`
+			INSERT INTO group_members (groupname, membername, role)
+			VALUES (?, ?, ?);
+		`, groupname, currentUser, RoleOwner)
 
 		if err != nil {
 			return fmt.Errorf("failed to add current user to group: %w", err)
 		}
 
 		log.Printf("current user added to new group %s %s", groupname, currentUser)
+
+		_, err = insertGroupSystemMessage(tx, groupname, SystemEvent{Type: SystemGroupCreated, Actor: currentUser})
+		if err != nil {
+			return err
+		}
+	case err != nil:
+		return err
 	}
 
 	// Add the other users to the group (if not already members)
 	for _, username := range usernames {
 		// Check if the user is already a member of the group
 		var userAlreadyMember int
-		err = db.c.QueryRow(`
+		err = tx.QueryRow(`
 			SELECT COUNT(*) 
 			FROM group_members 
 			WHERE groupname = ? AND membername = ?;
@@ -97,14 +112,26 @@ func (db *appdbimpl) AddToGroup(groupname string, usernames []string, currentUse
 		}
 
 		// Add the user to the group
-		_, err = db.c.Exec(`
-			INSERT INTO group_members (groupname, membername)
-			VALUES (?, ?);
-		`, groupname, username)
+		_, err = tx.Exec(`
+			INSERT INTO group_members (groupname, membername, role)
+			VALUES (?, ?, ?);
+		`, groupname, username, RoleMember)
 
 		if err != nil {
 			return fmt.Errorf("failed to add user %s to group: %w", username, err)
 		}
+
+		// Record the new member in the group conversation
+		_, err = insertGroupSystemMessage(tx, groupname, SystemEvent{Type: SystemMemberAdded, Actor: currentUser, Member: username})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	// Return nil if all users were successfully added
@@ -5,34 +5,20 @@ import (
 	"fmt"
 )
 
-// ChangeGroupName changes the name of an existing group in the database.
+// ChangeGroupName changes the name of an existing group in the database, and records it with a system message in the
+// group conversation.
 //
 // Parameters:
+// - currentUser: The user renaming the group. Only the owner and the admins of the group can rename it.
 // - oldGroupName: The current name of the group.
 // - newGroupName: The new name to be assigned to the group.
 //
 // Returns:
 // - error: If an error occurs during the process (e.g., group does not exist, new name is already taken, etc.), an error is returned.
-func (db *appdbimpl) ChangeGroupName(oldGroupName string, newGroupName string) error {
-	// Check if the old group exists
-	var groupExists int
-	err := db.c.QueryRow(`
-		SELECT COUNT(*) 
-		FROM groups 
-		WHERE groupname = ?;
-	`, oldGroupName).Scan(&groupExists)
-
-	if err != nil {
-		return fmt.Errorf("failed to check if old group exists: %w", err)
-	}
-
-	// If the group does not exist, return an error
-	if groupExists == 0 {
-		return fmt.Errorf("group with name '%s' does not exist", oldGroupName)
-	}
-
+// ErrGroupNotFound, ErrNotGroupMember or ErrNotGroupAdmin are returned if the change is not allowed.
+func (db *appdbimpl) ChangeGroupName(currentUser string, oldGroupName string, newGroupName string) error {
 	// Check if the new groupname already exists as a username
-	_, err = db.GetUser(newGroupName)
+	_, err := db.GetUser(newGroupName)
 
 	if err == nil {
 		return fmt.Errorf("group name already exists as a username")
@@ -40,9 +26,30 @@ func (db *appdbimpl) ChangeGroupName(oldGroupName string, newGroupName string) e
 		return fmt.Errorf("database error while checking username: %w", err)
 	}
 
+	tx, err := db.c.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	// Defer the rollback in case of any error, to ensure a clean up if something goes wrong
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
+
+	// Check if the old group exists and the user is allowed to rename it
+	err = requireGroupAdmin(tx, oldGroupName, currentUser)
+	if err != nil {
+		return err
+	}
+
 	// Check if the new group name already exists
 	var newGroupExists int
-	err = db.c.QueryRow(`
+	err = tx.QueryRow(`
 		SELECT COUNT(*) 
 		FROM groups 
 		WHERE groupname = ?;
@@ -54,18 +61,37 @@ func (db *appdbimpl) ChangeGroupName(oldGroupName string, newGroupName string) e
 
 	// If the new group name already exists, return an error
 	if newGroupExists > 0 {
-		return fmt.Errorf("group with name '%s' already exists", newGroupName)
+		err = fmt.Errorf("group with name '%s' already exists", newGroupName)
+		return err
 	}
 
-	// Now update the group name in the database
-	_, err = db.c.Exec(`
-		UPDATE groups 
-		SET groupname = ? 
-		WHERE groupname = ?;
-	`, newGroupName, oldGroupName)
+	// Now update the group name in the database, together with the rows referring to it
+	for _, query := range []string{
+		`UPDATE groups SET groupname = ? WHERE groupname = ?;`,
+		`UPDATE group_members SET groupname = ? WHERE groupname = ?;`,
+		`UPDATE conversations SET groupname = ? WHERE groupname = ?;`,
+	} {
+		_, err = tx.Exec(query, newGroupName, oldGroupName)
+		if err != nil {
+			return fmt.Errorf("failed to update group name: %w", err)
+		}
+	}
+
+	// Record the change in the group conversation
+	_, err = insertGroupSystemMessage(tx, newGroupName, SystemEvent{
+		Type:        SystemRenamed,
+		Actor:       currentUser,
+		RenamedFrom: oldGroupName,
+		RenamedTo:   newGroupName,
+	})
+	if err != nil {
+		return err
+	}
 
+	// Commit the transaction
+	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("failed to update group name: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	// Return nil if the group name was successfully changed
@@ -4,35 +4,41 @@ import (
 	"fmt"
 )
 
-// ChangeGroupPicture changes the photo of an existing group.
+// ChangeGroupPicture changes the photo of an existing group, and records it with a system message in the group
+// conversation.
 //
 // Parameters:
+// - currentUser: The username of the user who is attempting to change the group photo. Only the owner and the admins of the group can change it.
 // - groupName: The name of the group whose photo is being updated.
 // - newPhotoURL: The new photo URL that will be set as the group's photo.
-// - currentUser: The username of the user who is attempting to change the group photo. This ensures only authorized users can change the group photo.
 //
 // Returns:
 // - error: If an error occurs during the process (e.g., group does not exist, or update failure), an error is returned.
-func (db *appdbimpl) ChangeGroupPicture(groupName string, newPhotoURL string) error {
-	// Check if the group exists
-	var groupExists int
-	err := db.c.QueryRow(`
-		SELECT COUNT(*) 
-		FROM groups 
-		WHERE groupname = ?;
-	`, groupName).Scan(&groupExists)
-
+// ErrGroupNotFound, ErrNotGroupMember or ErrNotGroupAdmin are returned if the change is not allowed.
+func (db *appdbimpl) ChangeGroupPicture(currentUser string, groupName string, newPhotoURL string) error {
+	tx, err := db.c.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to check if group exists: %w", err)
+		return fmt.Errorf("failed to start transaction: %w", err)
 	}
 
-	// If the group does not exist, return an error
-	if groupExists == 0 {
-		return fmt.Errorf("group with name '%s' does not exist", groupName)
+	// Defer the rollback in case of any error, to ensure a clean up if something goes wrong
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
+
+	// Check if the group exists and the user is allowed to change it
+	err = requireGroupAdmin(tx, groupName, currentUser)
+	if err != nil {
+		return err
 	}
 
 	// Update the group's photo URL in the database
-	_, err = db.c.Exec(`
+	_, err = tx.Exec(`
 		UPDATE groups 
 		SET group_photo_url = ? 
 		WHERE groupname = ?;
@@ -42,6 +48,18 @@ func (db *appdbimpl) ChangeGroupPicture(groupName string, newPhotoURL string) er
 		return fmt.Errorf("failed to update group photo: %w", err)
 	}
 
+	// Record the change in the group conversation
+	_, err = insertGroupSystemMessage(tx, groupName, SystemEvent{Type: SystemPhotoChanged, Actor: currentUser})
+	if err != nil {
+		return err
+	}
+
+	// Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	// Return nil if the group's photo was successfully updated
 	return nil
 }
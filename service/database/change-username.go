@@ -48,18 +48,54 @@ func (db *appdbimpl) ChangeUsername(oldUsername, newUsername string) error {
 		return fmt.Errorf("failed to update username in messages: %w", err)
 	}
 
+	// Update the original sender recorded on the forwarded copies of the user's messages
+	_, err = tx.Exec("UPDATE messages SET forwarded_from_sender = ? WHERE forwarded_from_sender = ?", newUsername, oldUsername)
+	if err != nil {
+		return fmt.Errorf("failed to update username in forwarded messages: %w", err)
+	}
+
+	// Update the username of the user's reactions
+	_, err = tx.Exec("UPDATE comments SET reactor_username = ? WHERE reactor_username = ?", newUsername, oldUsername)
+	if err != nil {
+		return fmt.Errorf("failed to update username in comments: %w", err)
+	}
+
+	// Update the username of the user's conversation settings
+	_, err = tx.Exec("UPDATE conversation_settings SET username = ? WHERE username = ?", newUsername, oldUsername)
+	if err != nil {
+		return fmt.Errorf("failed to update username in conversation_settings: %w", err)
+	}
+
+	// Update the username of the messages the user hid for themselves
+	_, err = tx.Exec("UPDATE hidden_messages SET username = ? WHERE username = ?", newUsername, oldUsername)
+	if err != nil {
+		return fmt.Errorf("failed to update username in hidden_messages: %w", err)
+	}
+
 	// Update the username in message_status table
 	_, err = tx.Exec("UPDATE message_status SET user_id = ? WHERE user_id = ?", newUsername, oldUsername)
 	if err != nil {
 		return fmt.Errorf("failed to update username in message_status: %w", err)
 	}
 
+	// Update the username of the user's sessions, so that they stay logged in
+	_, err = tx.Exec("UPDATE sessions SET username = ? WHERE username = ?", newUsername, oldUsername)
+	if err != nil {
+		return fmt.Errorf("failed to update username in sessions: %w", err)
+	}
+
 	// Update the username in the group_members table for all the groups the user is a member of
 	_, err = tx.Exec("UPDATE group_members SET membername = ? WHERE membername = ?", newUsername, oldUsername)
 	if err != nil {
 		return fmt.Errorf("failed to update username in group_members: %w", err)
 	}
 
+	// Update the owner of the files the user uploaded
+	_, err = tx.Exec("UPDATE media SET owner = ? WHERE owner = ?", newUsername, oldUsername)
+	if err != nil {
+		return fmt.Errorf("failed to update username in media: %w", err)
+	}
+
 	// Commit the transaction
 	err = tx.Commit()
 	if err != nil {
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// CreateMedia records an uploaded file. The number of references and the creation time of `m` are ignored: a new file
+// has no references, and is collected by the media janitor if it is not used within the grace period.
+//
+// If a file with the same name (and so the same content) was already recorded, the existing record is kept, but an
+// unused file gets a new grace period: it was just uploaded again, and its URL handed out to the uploader. A file that
+// the media janitor is already removing stays marked as such (see MarkOrphanedMedia).
+//
+// Returns:
+// - An error if the insertion fails.
+func (db *appdbimpl) CreateMedia(m Media) error {
+	variantOf := sql.NullString{String: m.VariantOf, Valid: m.VariantOf != ""}
+	_, err := db.c.Exec(`
+		INSERT INTO media (name, owner, size, mime_type, variant_of, unreferenced_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (name) DO UPDATE SET unreferenced_at = CASE WHEN refs <= 0 THEN CURRENT_TIMESTAMP ELSE NULL END`,
+		m.Name, m.Owner, m.Size, m.MimeType, variantOf)
+	if err != nil {
+		return fmt.Errorf("error recording media %q: %w", m.Name, err)
+	}
+	return nil
+}
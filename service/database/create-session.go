@@ -0,0 +1,36 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// CreateSession stores a new session for the user, valid for `ttl`.
+// Only a hash of the token is stored, so a leaked database does not leak usable tokens.
+// Expired sessions of the user are removed at the same time.
+func (db *appdbimpl) CreateSession(username string, token string, ttl time.Duration) error {
+	_, err := db.c.Exec(`
+		DELETE FROM sessions
+		WHERE username = ? AND expires_at <= CURRENT_TIMESTAMP`, username)
+	if err != nil {
+		return fmt.Errorf("failed to remove expired sessions: %w", err)
+	}
+
+	_, err = db.c.Exec(`
+		INSERT INTO sessions (token_hash, username, expires_at)
+		VALUES (?, ?, datetime('now', ?))`,
+		hashToken(token), username, fmt.Sprintf("+%d seconds", int64(ttl/time.Second)))
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+// hashToken returns the representation of a session token stored in the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,7 +1,11 @@
 package database
 
+import "database/sql"
+
 // CreateUser inserts a new user into the database.
-func (db *appdbimpl) CreateUser(username string, profilePhotoURL string, authToken int) error {
-	_, err := db.c.Exec("INSERT INTO users (username, profile_photo_url, auth_token) VALUES (?, ?, ?)", username, profilePhotoURL, authToken)
+// `passwordHash` is the encoded hash of the user's password, or an empty string if the user has none.
+func (db *appdbimpl) CreateUser(username string, profilePhotoURL string, passwordHash string) error {
+	_, err := db.c.Exec("INSERT INTO users (username, profile_photo_url, password_hash) VALUES (?, ?, ?)",
+		username, profilePhotoURL, sql.NullString{String: passwordHash, Valid: passwordHash != ""})
 	return err
 }
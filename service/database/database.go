@@ -2,8 +2,14 @@
 Package database is the middleware between the app database and the code. All data (de)serialization (save/load) from a
 persistent database are handled here. Database specific logic should never escape this package.
 
-To use this package you need to apply migrations to the database if needed/wanted, connect to it (using the database
-data source name from config), and then initialize an instance of AppDatabase from the DB connection.
+To use this package you need to connect to the database (using the database data source name from config), and then
+initialize an instance of AppDatabase from the DB connection. New applies the schema migrations embedded in the
+executable (see the `migrations` directory): each migration runs in its own transaction, and the database version is
+tracked in the `schema_version` table. New refuses to work with a database migrated by a newer executable.
+
+Message search uses the SQLite FTS5 extension, which the SQLite driver only includes when the executable is built with
+the `sqlite_fts5` tag (`go build -tags sqlite_fts5 ./cmd/webapi`). Without it, everything else works, and
+SearchMessages returns ErrSearchUnavailable.
 
 For example, this code adds a parameter in `webapi` executable for the database data source name (add it to the
 main.WebAPIConfiguration structure):
@@ -12,7 +18,7 @@ main.WebAPIConfiguration structure):
 		Filename string `conf:""`
 	}
 
-This is an example on how to migrate the DB and connect to it:
+This is an example on how to connect to the DB:
 
 	// Start Database
 	logger.Println("initializing database support")
@@ -34,6 +40,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 )
 
 // AppDatabase is the high level interface for the DB
@@ -41,42 +48,70 @@ type AppDatabase interface {
 
 	//Securityralated Functions
 	GetUsernameByToken(token string) (string, error)
+	CreateSession(username string, token string, ttl time.Duration) error
+	DeleteSession(token string) error
+	GetPasswordHash(username string) (string, error)
+	SetPasswordHash(username string, passwordHash string) error
 
 	// Userrelated Functions
 	GetUser(username string) (*User, error)
-	CreateUser(username string, profilePhotoURL string, authToken int) error
-	SearchUser(partialUsername string) ([]User, error)
-	LoadUserConversations(username string) ([]ConversationPreview, error)
+	CreateUser(username string, profilePhotoURL string, passwordHash string) error
+	SearchUser(currentUser string, partialUsername string, limit int, offset int) ([]User, error)
+	LoadUserConversations(username string, includeArchived bool) ([]ConversationPreview, error)
+	SetConversationSettings(username, partnerName string, settings ConversationSettings) error
 	ChangeUsername(oldUsername, newUsername string) error
 	ChangeProfilePicture(username, newProfilePhotoURL string) error
 	GetGroupByName(groupName string) (*Group, error)
 
+	// Media Functions
+	CreateMedia(m Media) error
+	GetMedia(name string) (*Media, error)
+	IsPublicMedia(name string) (bool, error)
+	ListOrphanedMedia(gracePeriod time.Duration, limit int) ([]string, error)
+	MarkOrphanedMedia(name string, gracePeriod time.Duration) ([]Media, error)
+	DeleteMarkedMedia(name string) error
+	UnmarkMedia(name string) error
+
 	// Conversation Functions
-	ShowConversation(username, conversationPartnerName string) ([]ConversationDetail, error)
+	ShowConversation(username, conversationPartnerName string, page PageRequest) (*ConversationPage, error)
 	SendMessage(msg NewMessage) (int, error)
-	DeleteMessage(currentUser string, messageID int) error
-	MarkAllMessagesAsReceived(partnerUsername string, username string) error
-	MarkAllMessagesAsRead(username string, partnerUsername string) error
-	GetMessage(messageID *int) (*ConversationDetail, error)
+	ForwardMessage(currentUser string, messageID int, recipients []string) ([]int, error)
+	DeleteMessage(currentUser string, messageID int, deleteWindow time.Duration) error
+	HideMessage(username string, messageID int) error
+	EditMessage(currentUser string, messageID int, newContent string, editWindow time.Duration) error
+	GetMessageEdits(messageID int) ([]MessageEdit, error)
+	GetMessageReceipts(messageID int) ([]Receipt, error)
+	MarkMessagesAsReceived(username string, partnerUsername string, upTo int) (int, error)
+	MarkMessagesAsRead(username string, partnerUsername string, upTo int) (int, error)
+	GetMessage(messageID *int, currentUser string) (*ConversationDetail, error)
+	GetConversationMembers(username, partnerName string) (*ConversationMembers, error)
+	GetMessageConversationMembers(messageID int) (*ConversationMembers, error)
+	SearchMessages(username string, query string, limit int, offset int) ([]MessageSearchHit, bool, error)
 
 	// Comment Functions
 	AddComment(messageID int, currentUser string, content string) error
-	DeleteComment(messageID int, reactorUsername string) error
+	DeleteComment(messageID int, reactorUsername string, content string) error
 
 	// Group Functions
 	AddToGroup(groupname string, usernames []string, currentUser string) error
-	ChangeGroupPicture(groupName string, newPhotoURL string) error
-	ChangeGroupName(oldGroupName string, newGroupName string) error
+	ChangeGroupPicture(currentUser string, groupName string, newPhotoURL string) error
+	ChangeGroupName(currentUser string, oldGroupName string, newGroupName string) error
 	LeaveGroup(groupName string, currentUser string) error
+	ListGroupMembers(groupname string) ([]GroupMember, error)
+	SetGroupRole(currentUser string, groupname string, member string, role string) error
+	RemoveFromGroup(currentUser string, groupname string, member string) (int, error)
 
 	GetName() (string, error)
 	SetName(name string) error
 
+	SearchAvailable() bool
+
 	Ping() error
 }
 
 type appdbimpl struct {
-	c *sql.DB
+	c    *sql.DB
+	fts5 bool // Whether SQLite includes FTS5, see SearchAvailable
 }
 
 // New returns a new instance of AppDatabase based on the SQLite connection `db`.
@@ -86,96 +121,31 @@ func New(db *sql.DB) (AppDatabase, error) {
 		return nil, errors.New("database is required when building an AppDatabase")
 	}
 
-	// List of Tablenames and their SQL-CREATE-Commands
-	tables := map[string]string{
-		"users": `
-			CREATE TABLE IF NOT EXISTS users (
-				username TEXT UNIQUE NOT NULL PRIMARY KEY,
-				profile_photo_url TEXT,
-				auth_token INTEGER UNIQUE NOT NULL
-			);
-		`,
-		"conversations": `
-			CREATE TABLE IF NOT EXISTS conversations (
-				id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-				user1 TEXT REFERENCES users(username) ON DELETE CASCADE,
-				user2 TEXT REFERENCES users(username) ON DELETE CASCADE,
-				groupname TEXT REFERENCES groups(groupname) ON DELETE CASCADE
-			);
-		`,
-		"messages": `
-			CREATE TABLE IF NOT EXISTS messages (
-				id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-				content TEXT,
-				sender TEXT REFERENCES users(username) ON DELETE CASCADE,
-				is_photo BOOLEAN DEFAULT FALSE,
-				is_forwarded BOOLEAN DEFAULT FALSE,
-				created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-				conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE
-			);
-		`,
-		"message_status": `
-			CREATE TABLE IF NOT EXISTS message_status (
-				message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
-				user_id TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
-				received BOOLEAN DEFAULT FALSE,
-				read BOOLEAN DEFAULT FALSE,
-				PRIMARY KEY (message_id, user_id)
-			);
-		`,
-		"comments": `
-			CREATE TABLE IF NOT EXISTS comments (
-				id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
-				reactor_username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
-				message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
-				content TEXT,
-				UNIQUE (reactor_username, message_id)
-			);
-		`,
-		"groups": `
-			CREATE TABLE IF NOT EXISTS groups (
-				groupname TEXT NOT NULL PRIMARY KEY,
-				group_photo_url TEXT
-			);
-		`,
-		"group_members": `
-			CREATE TABLE IF NOT EXISTS group_members (
-				groupname TEXT NOT NULL REFERENCES groups(groupname) ON DELETE CASCADE,
-				membername TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE
-			);
-		`,
+	// Bring the schema to the latest version embedded in the executable
+	if err := migrate(db); err != nil {
+		return nil, fmt.Errorf("error migrating database schema: %w", err)
 	}
 
-	// Check if table exists. If not, the database will be created
-
-	for tableName, createStmt := range tables {
-		_, err := db.Exec(createStmt)
-		if err != nil {
-			fmt.Printf("SQL Error: Failed to create table %s. Statement: %s, Error: %v\n", tableName, createStmt, err)
-			return nil, fmt.Errorf("error creating table %s: %w", tableName, err)
-		}
+	// Message search needs FTS5, which go-sqlite3 only compiles in with the `sqlite_fts5` build tag
+	fts5, err := fts5Available(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := setupSearchIndex(db, fts5); err != nil {
+		return nil, fmt.Errorf("error setting up message search: %w", err)
 	}
-
-	/*
-		// Check if table exists. If not, the database is empty, and we need to create the structure
-
-		var tableName string
-		err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type='table' AND name='example_table';`).Scan(&tableName)
-		if errors.Is(err, sql.ErrNoRows) {
-			sqlStmt := `CREATE TABLE example_table (id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT, name TEXT);`
-			_, err = db.Exec(sqlStmt)
-			if err != nil {
-				return nil, fmt.Errorf("error creating database structure: %w", err)
-			}
-		}
-
-	*/
 
 	return &appdbimpl{
-		c: db,
+		c:    db,
+		fts5: fts5,
 	}, nil
 }
 
+// SearchAvailable reports whether the executable was built with FTS5, which SearchMessages needs.
+func (db *appdbimpl) SearchAvailable() bool {
+	return db.fts5
+}
+
 func (db *appdbimpl) Ping() error {
 	return db.c.Ping()
 }
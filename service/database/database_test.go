@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// newTestConn opens a new, empty SQLite database in a temporary file, closed at the end of the test.
+func newTestConn(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// newTestDB returns an AppDatabase on a new database, migrated to the latest schema.
+func newTestDB(t *testing.T) *appdbimpl {
+	t.Helper()
+	db, err := New(newTestConn(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db.(*appdbimpl)
+}
+
+// exec runs statements on the test database, failing the test on errors.
+func exec(t *testing.T, db *sql.DB, query string, args ...interface{}) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// createUsers creates users without password.
+func createUsers(t *testing.T, db *appdbimpl, usernames ...string) {
+	t.Helper()
+	for _, username := range usernames {
+		if err := db.CreateUser(username, "", ""); err != nil {
+			t.Fatalf("CreateUser(%s): %v", username, err)
+		}
+	}
+}
+
+// sendMessage sends a text message and returns its id.
+func sendMessage(t *testing.T, db *appdbimpl, from string, to string, content string) int {
+	t.Helper()
+	id, err := db.SendMessage(NewMessage{FromUser: from, ToUser: to, Content: content})
+	if err != nil {
+		t.Fatalf("SendMessage(%s → %s): %v", from, to, err)
+	}
+	return id
+}
+
+// messageIDs returns the ids of the messages of a page, in order.
+func messageIDs(messages []ConversationDetail) []int {
+	ids := make([]int, len(messages))
+	for i, m := range messages {
+		ids[i] = m.MessageID
+	}
+	return ids
+}
+
+// groupRoles returns the role of each member of a group.
+func groupRoles(t *testing.T, db *appdbimpl, groupname string) map[string]string {
+	t.Helper()
+	members, err := db.ListGroupMembers(groupname)
+	if err != nil {
+		t.Fatalf("ListGroupMembers(%s): %v", groupname, err)
+	}
+	roles := make(map[string]string, len(members))
+	for _, m := range members {
+		roles[m.Username] = m.Role
+	}
+	return roles
+}
+
+// lastSystemEvent returns the event of the newest message of a group conversation, which must be a system message.
+func lastSystemEvent(t *testing.T, db *appdbimpl, viewer string, groupname string) SystemEvent {
+	t.Helper()
+	page, err := db.ShowConversation(viewer, groupname, PageRequest{Limit: 1})
+	if err != nil {
+		t.Fatalf("ShowConversation(%s): %v", groupname, err)
+	}
+	if len(page.Messages) == 0 || page.Messages[0].System == nil {
+		t.Fatalf("the last message of %s is not a system message: %+v", groupname, page.Messages)
+	}
+	return *page.Messages[0].System
+}
@@ -12,25 +12,105 @@ type ConversationPreview struct {
 	PhotoURL        sql.NullString `json:"photo_url"`         // Profile Photo URL
 	LastMessage     sql.NullString `json:"last_message"`      // Snippet or Photo Icon
 	LastMessageTime sql.NullTime   `json:"last_message_time"` // Timestamp of Last Message
+	// Sender of the last message, empty for system messages and conversations without messages
+	LastMessageSender    string `json:"last_message_sender"`
+	LastMessageFullyRead bool   `json:"last_message_fully_read"` // Whether all recipients read the last message
+	UnreadCount          int    `json:"unread_count"`            // Number of messages not yet read by the user
+	ConversationSettings
+}
+
+// ConversationSettings are the settings of a conversation for one of its participants.
+type ConversationSettings struct {
+	Archived   bool       `json:"archived"`    // Whether the conversation is archived, until a new message is received
+	MutedUntil *time.Time `json:"muted_until"` // When the conversation stops being muted, null if it is not muted
+	PinOrder   *int       `json:"pin_order"`   // Position among the pinned conversations, lowest first, null if not pinned
 }
 
 // ConversationDetail represents the detailed information for a message in a conversation.
 type ConversationDetail struct {
-	MessageID     int        `json:"message_id"`     // ID of the message
-	Content       string     `json:"content"`        // Content of the message (text or photo URL)
-	Sender        string     `json:"sender"`         // Sender of the message
-	IsPhoto       bool       `json:"is_photo"`       // Whether the message is a photo message
-	IsForwarded   bool       `json:"is_forwarded"`   // Whether the message is a photo message
-	Timestamp     time.Time  `json:"timestamp"`      // Timestamp of when the message was created
-	FullyReceived bool       `json:"fully_received"` // Received-Status of the message
-	FullyRead     bool       `json:"fully_read"`     // Read-Status of the message
-	Reactions     []Reaction `json:"reactions"`      // List of user reactions (comments)
-}
-
-// Reaction represents the Reaction on a message in a conversation.
+	MessageID     int           `json:"message_id"`     // ID of the message
+	Content       string        `json:"content"`        // Content of the message (text or photo URL)
+	Sender        string        `json:"sender"`         // Sender of the message, empty for system messages
+	IsPhoto       bool          `json:"is_photo"`       // Whether the message is a photo message
+	IsForwarded   bool          `json:"is_forwarded"`   // Whether the message is a forwarded copy of another message
+	Timestamp     time.Time     `json:"timestamp"`      // Timestamp of when the message was created
+	EditedAt      *time.Time    `json:"edited_at"`      // Timestamp of the last edit, null if never edited
+	DeletedAt     *time.Time    `json:"deleted_at"`     // When the message was deleted for everyone, its content is then empty
+	FullyReceived bool          `json:"fully_received"` // Received-Status of the message
+	FullyRead     bool          `json:"fully_read"`     // Read-Status of the message
+	ReceivedAt    *time.Time    `json:"received_at"`    // When the recipient of a 1:1 message received it, null in groups or if unknown
+	ReadAt        *time.Time    `json:"read_at"`        // When the recipient of a 1:1 message read it, null in groups or if unknown
+	Reactions     []Reaction    `json:"reactions"`      // Reactions (comments), one per emoji, in order of first use
+	ReplyTo       *ReplyPreview `json:"reply_to"`       // Quoted preview of the message this one replies to, null if not a reply
+	Kind          string        `json:"kind"`           // MessageKindUser or MessageKindSystem
+	System        *SystemEvent  `json:"system"`         // Event recorded by a system message, null for user messages
+	// Provenance of a forwarded message, null if the message is not forwarded or was forwarded before it was recorded
+	ForwardedFrom *ForwardInfo `json:"forwarded_from"`
+}
+
+// ForwardInfo is the provenance of a forwarded message. Forwarding a forwarded message keeps the original message
+// and sender, and increases the count.
+type ForwardInfo struct {
+	MessageID    int    `json:"message_id"`    // ID of the original message, which may have been deleted since
+	Sender       string `json:"sender"`        // Sender of the original message
+	ForwardCount int    `json:"forward_count"` // How many times the message was forwarded to reach this copy
+}
+
+// ReplyPreview is the quoted preview of the message a reply refers to.
+// If the original message was deleted, only MessageID (and Sender, if deleted for everyone) is set and Deleted is true.
+type ReplyPreview struct {
+	MessageID int    `json:"message_id"` // ID of the original message
+	Sender    string `json:"sender"`     // Sender of the original message
+	Snippet   string `json:"snippet"`    // Beginning of the original content (text or photo URL)
+	IsPhoto   bool   `json:"is_photo"`   // Whether the original message is a photo message
+	Deleted   bool   `json:"deleted"`    // Whether the original message was deleted
+}
+
+// ConversationPage is a page of messages of a conversation, newest first.
+type ConversationPage struct {
+	Messages   []ConversationDetail `json:"messages"`    // Messages of the page
+	NextCursor *int                 `json:"next_cursor"` // Cursor of the following page, null on the last page
+}
+
+// MessageSearchHit is a message found by a full-text search.
+type MessageSearchHit struct {
+	MessageID        int       `json:"message_id"`        // ID of the message
+	ConversationName string    `json:"conversation_name"` // Partner username or group name of the conversation
+	IsGroup          bool      `json:"is_group"`          // Whether the conversation is a group
+	Sender           string    `json:"sender"`            // Sender of the message
+	Snippet          string    `json:"snippet"`           // Matching part of the message, HTML escaped, matches in <mark>
+	Timestamp        time.Time `json:"timestamp"`         // Timestamp of when the message was created
+}
+
+// PageRequest selects a page of a conversation. At most one of Before and After is set.
+type PageRequest struct {
+	Before int // Only messages older than this message id (0 if unset)
+	After  int // Only messages newer than this message id (0 if unset)
+	Limit  int // Maximum number of messages in the page
+}
+
+// Receipt is the delivery and read status of a message for one of its recipients.
+// The times are null while the message is not received/read, and for statuses recorded before they were tracked.
+type Receipt struct {
+	Username   string     `json:"username"`    // Recipient of the message
+	Received   bool       `json:"received"`    // Whether the recipient received the message
+	ReceivedAt *time.Time `json:"received_at"` // When the recipient received the message
+	Read       bool       `json:"read"`        // Whether the recipient read the message
+	ReadAt     *time.Time `json:"read_at"`     // When the recipient read the message
+}
+
+// Reaction represents the users who reacted to a message in a conversation with the same emoji.
 type Reaction struct {
-	Reactor string `json:"reactor"` // username of the Reactor
-	Content string `json:"content"` // Content of the Reaction (e.g. Emoji)
+	Content  string   `json:"content"`  // Content of the Reaction (an emoji)
+	Count    int      `json:"count"`    // How many users reacted with the emoji
+	Reacted  bool     `json:"reacted"`  // Whether the user who loaded the message reacted with the emoji
+	Reactors []string `json:"reactors"` // Usernames of the Reactors, in reaction order
+}
+
+// MessageEdit is a previous version of an edited message.
+type MessageEdit struct {
+	Content  string    `json:"content"`   // Content of the message before the edit
+	EditedAt time.Time `json:"edited_at"` // Timestamp of the edit that replaced this content
 }
 
 // NewMessage is used for the Parameters of the SendMessage Function
@@ -40,13 +120,20 @@ type NewMessage struct {
 	Content     string
 	IsPhoto     bool
 	IsForwarded bool
+	ReplyTo     int          // ID of the message this one replies to, 0 if it is not a reply
+	Forward     *ForwardInfo // Provenance of a forwarded message, nil if the message is not forwarded
 }
 
 // User represents a user in the database.
 type User struct {
 	Username        string `json:"username"`
 	ProfilePhotoURL string `json:"profile_photo_url"`
-	AuthToken       int    `json:"auth_token"`
+}
+
+// ConversationMembers lists the participants of a conversation.
+type ConversationMembers struct {
+	Groupname string   // Name of the group, empty for 1:1 conversations
+	Usernames []string // Usernames of all participants
 }
 
 // Group represents a group in the database.
@@ -54,3 +141,22 @@ type Group struct {
 	Groupname     string `json:"groupname"`
 	GroupPhotoUrl string `json:"group_photo_uRL"`
 }
+
+// GroupMember is a member of a group with its role (RoleOwner, RoleAdmin or RoleMember).
+type GroupMember struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// Media is an uploaded file.
+type Media struct {
+	Name      string    `json:"name"`       // Storage name: hash of the content and file extension
+	Owner     string    `json:"owner"`      // User who first uploaded the file
+	Size      int64     `json:"size"`       // Size in bytes
+	MimeType  string    `json:"mime_type"`  // MIME type of the content
+	Refs      int       `json:"refs"`       // Number of messages, profiles and groups using the file
+	VariantOf string    `json:"variant_of"` // Name of the image this file is a variant of, empty for originals
+	CreatedAt time.Time `json:"created_at"` // When the file was first uploaded
+	// When the media janitor started removing the file from the storage, nil if it is not being removed
+	DeletingAt *time.Time `json:"deleting_at"`
+}
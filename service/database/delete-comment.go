@@ -1,23 +1,28 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 )
 
+// ErrCommentNotFound is returned when the user has no such reaction on the message.
+var ErrCommentNotFound = errors.New("comment not found")
+
 // DeleteComment removes a user's comment from a message.
 //
 // Parameters:
 // - messageID: The ID of the message the comment is associated with.
 // - reactorUsername: The username of the user who posted the comment.
+// - content: The emoji of the reaction to remove, or an empty string to remove all the user's reactions.
 //
 // Returns:
-// - error: If the comment is not found or a database error occurs.
-func (db *appdbimpl) DeleteComment(messageID int, reactorUsername string) error {
+// - error: ErrCommentNotFound if the user has no such reaction, or an error if a database error occurs.
+func (db *appdbimpl) DeleteComment(messageID int, reactorUsername string, content string) error {
 	// Execute the DELETE statement
 	res, err := db.c.Exec(`
 		DELETE FROM comments
-		WHERE message_id = ? AND reactor_username = ?;
-	`, messageID, reactorUsername)
+		WHERE message_id = ? AND reactor_username = ? AND (? = '' OR content = ?);
+	`, messageID, reactorUsername, content, content)
 
 	if err != nil {
 		return fmt.Errorf("failed to delete comment: %w", err)
@@ -30,7 +35,7 @@ func (db *appdbimpl) DeleteComment(messageID int, reactorUsername string) error
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("comment not found or user not authorized")
+		return ErrCommentNotFound
 	}
 
 	return nil
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"fmt"
+)
+
+// DeleteMarkedMedia deletes the record of an uploaded file marked by MarkOrphanedMedia, once the file was removed from
+// the media storage. A record that is no longer marked is kept.
+//
+// Returns:
+// - An error if the deletion fails.
+func (db *appdbimpl) DeleteMarkedMedia(name string) error {
+	_, err := db.c.Exec(`DELETE FROM media WHERE name = ? AND deleting_at IS NOT NULL`, name)
+	if err != nil {
+		return fmt.Errorf("error deleting media %q: %w", name, err)
+	}
+	return nil
+}
@@ -2,19 +2,31 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 )
 
-// DeleteMessage removes a message from the database if the user has permission to do so.
-// It ensures that only the sender can delete their own messages.
+var (
+	ErrMessageDeleted      = errors.New("message was deleted")
+	ErrDeleteWindowExpired = errors.New("message can no longer be deleted for everyone")
+)
+
+// DeleteMessage deletes a message for everyone: the message is kept as a tombstone, whose content, reactions and edit
+// history are removed. It ensures that only the sender can delete their own messages, within `deleteWindow` from when
+// the message was sent, except for the owner and the admins of a group, who can delete any message of the group at
+// any time.
 //
 // Parameters:
 // - currentUser: The user attempting to delete the message.
 // - messageID: The unique identifier of the message to be deleted.
+// - deleteWindow: How long after sending its sender can delete a message.
 //
 // Returns:
-// - An error if the deletion fails or if the user does not have permission.
-func (db *appdbimpl) DeleteMessage(currentUser string, messageID int) error {
+// - ErrMessageNotFound or ErrNotMessageSender if the message does not exist or the user does not have permission,
+// ErrDeleteWindowExpired if the sender can no longer delete it, ErrMessageDeleted if it is already deleted, or an
+// error if the deletion fails.
+func (db *appdbimpl) DeleteMessage(currentUser string, messageID int, deleteWindow time.Duration) error {
 	tx, err := db.c.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to start transaction: %w", err)
@@ -30,34 +42,74 @@ func (db *appdbimpl) DeleteMessage(currentUser string, messageID int) error {
 		}
 	}()
 
-	var sender sql.NullString
+	var sender, groupname sql.NullString
+	var deleted, withinWindow bool
 
 	// Step 1: Retrieve the message and check if the user has permission to delete it
 	err = tx.QueryRow(`
-        SELECT m.id, sender
+        SELECT m.id, sender, c.groupname, m.deleted_at IS NOT NULL, m.created_at > datetime('now', ?)
         FROM messages m
+        JOIN conversations c ON c.id = m.conversation_id
         WHERE m.id = ?`,
-		messageID).Scan(&messageID, &sender)
+		fmt.Sprintf("-%d seconds", int64(deleteWindow/time.Second)), messageID).Scan(&messageID, &sender, &groupname,
+		&deleted, &withinWindow)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("message not found or no permissions to delete")
+			err = ErrMessageNotFound
+			return err
 		}
 		return fmt.Errorf("failed to find message: %w", err)
 	}
+	if deleted {
+		err = ErrMessageDeleted
+		return err
+	}
 
-	// Step 2: Verify that the current user is the sender of the message
-	if sender.String != currentUser {
-		return fmt.Errorf("user is not allowed to delete this message")
+	// Step 2: Verify that the current user moderates the group, or is the sender of the message within the window
+	moderator := false
+	if groupname.Valid {
+		err = requireGroupAdmin(tx, groupname.String, currentUser)
+		if err == nil {
+			moderator = true
+		} else if errors.Is(err, ErrNotGroupMember) || errors.Is(err, ErrNotGroupAdmin) {
+			err = nil
+		} else {
+			return err
+		}
+	}
+	if !moderator {
+		if sender.String != currentUser {
+			err = ErrNotMessageSender
+			return err
+		}
+		if !withinWindow {
+			err = ErrDeleteWindowExpired
+			return err
+		}
 	}
 
-	// Step 3: Delete the message (cascading delete will handle related records)
-	_, err = tx.Exec(`DELETE FROM messages WHERE id = ?`, messageID)
+	// Step 3: Replace the message with a tombstone. The triggers remove its text from the search index and release
+	// its photo.
+	_, err = tx.Exec(`
+		UPDATE messages
+		SET content = '', is_photo = FALSE, payload = NULL, deleted_at = CURRENT_TIMESTAMP
+		WHERE id = ?`, messageID)
 	if err != nil {
 		return fmt.Errorf("failed to delete message: %w", err)
 	}
 
-	// Step 4: Commit the transaction
+	// Step 4: Delete the edit history and the reactions, which would disclose the deleted message
+	_, err = tx.Exec(`DELETE FROM message_edits WHERE message_id = ?`, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to delete message edits: %w", err)
+	}
+	_, err = tx.Exec(`DELETE FROM comments WHERE message_id = ?`, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to delete message reactions: %w", err)
+	}
+
+	// Step 5: Commit the transaction
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
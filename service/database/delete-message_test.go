@@ -0,0 +1,82 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const testDeleteWindow = time.Hour
+
+func TestDeleteMessageLeavesTombstone(t *testing.T) {
+	db := newTestDB(t)
+	createUsers(t, db, "alice", "bob")
+	id := sendMessage(t, db, "alice", "bob", "first version")
+	if err := db.EditMessage("alice", id, "second version", time.Hour); err != nil {
+		t.Fatalf("EditMessage: %v", err)
+	}
+	if err := db.AddComment(id, "bob", "👍"); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+
+	if err := db.DeleteMessage("alice", id, testDeleteWindow); err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+	message, err := db.GetMessage(&id, "bob")
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if message.Content != "" || message.DeletedAt == nil || len(message.Reactions) != 0 {
+		t.Errorf("deleted message %+v, want empty content, deletion time and no reactions", message)
+	}
+	if edits, err := db.GetMessageEdits(id); err != nil || len(edits) != 0 {
+		t.Errorf("GetMessageEdits: %v, %v, want no edits", edits, err)
+	}
+
+	// The tombstone can not be changed anymore
+	if err := db.DeleteMessage("alice", id, testDeleteWindow); !errors.Is(err, ErrMessageDeleted) {
+		t.Errorf("DeleteMessage again: got %v, want ErrMessageDeleted", err)
+	}
+	if err := db.EditMessage("alice", id, "third version", time.Hour); !errors.Is(err, ErrMessageDeleted) {
+		t.Errorf("EditMessage: got %v, want ErrMessageDeleted", err)
+	}
+	if _, err := db.ForwardMessage("bob", id, []string{"alice"}); !errors.Is(err, ErrMessageDeleted) {
+		t.Errorf("ForwardMessage: got %v, want ErrMessageDeleted", err)
+	}
+	if err := db.AddComment(id, "bob", "😮"); !errors.Is(err, ErrMessageDeleted) {
+		t.Errorf("AddComment: got %v, want ErrMessageDeleted", err)
+	}
+}
+
+func TestDeleteMessagePermissions(t *testing.T) {
+	db := newGroupDB(t)
+	if err := db.SetGroupRole("alice", "friends", "bob", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	createUsers(t, db, "eve")
+	direct := sendMessage(t, db, "carol", "eve", "direct")
+	old := sendMessage(t, db, "carol", "friends", "old")
+	recent := sendMessage(t, db, "carol", "friends", "recent")
+	exec(t, db.c, `UPDATE messages SET created_at = datetime('now', '-2 hours') WHERE id IN (?, ?)`, direct, old)
+
+	tests := []struct {
+		name      string
+		user      string
+		messageID int
+		want      error
+	}{
+		{"other member", "dave", recent, ErrNotMessageSender},
+		{"non member", "eve", recent, ErrNotMessageSender},
+		{"recipient of a direct message", "eve", direct, ErrNotMessageSender},
+		{"sender after the window", "carol", old, ErrDeleteWindowExpired},
+		{"sender of a direct message after the window", "carol", direct, ErrDeleteWindowExpired},
+		{"missing message", "carol", 1000, ErrMessageNotFound},
+		{"sender within the window", "carol", recent, nil},
+		{"admin after the window", "bob", old, nil},
+	}
+	for _, tt := range tests {
+		if err := db.DeleteMessage(tt.user, tt.messageID, testDeleteWindow); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// DeleteSession revokes the session identified by the token (logout).
+// Other sessions of the same user are not affected.
+func (db *appdbimpl) DeleteSession(token string) error {
+	res, err := db.c.Exec(`DELETE FROM sessions WHERE token_hash = ?`, hashToken(token))
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNotMessageSender  = errors.New("user is not the sender of the message")
+	ErrPhotoMessage      = errors.New("photo messages can not be edited")
+	ErrEditWindowExpired = errors.New("message can no longer be edited")
+	ErrMessageUnchanged  = errors.New("new content is the same as the current one")
+)
+
+// EditMessage replaces the text of a message, keeping the previous version in `message_edits`.
+// Only the sender can edit a message, only text messages can be edited, and only within `editWindow` from when the
+// message was sent.
+//
+// Parameters:
+// - currentUser: The user attempting to edit the message.
+// - messageID: The unique identifier of the message to be edited.
+// - newContent: The new text of the message.
+// - editWindow: How long after sending a message can be edited.
+//
+// Returns:
+// - ErrMessageNotFound, ErrNotMessageSender, ErrMessageDeleted, ErrPhotoMessage, ErrEditWindowExpired or
+// ErrMessageUnchanged if the edit is not allowed, or an error if the database operation fails.
+func (db *appdbimpl) EditMessage(currentUser string, messageID int, newContent string, editWindow time.Duration) error {
+	tx, err := db.c.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	// Defer the rollback in case of any error, to ensure a clean up if something goes wrong
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
+
+	// Step 1: Retrieve the message and check whether it can still be edited
+	var sender sql.NullString
+	var content string
+	var isPhoto, deleted, withinWindow bool
+	err = tx.QueryRow(`
+		SELECT sender, content, is_photo, deleted_at IS NOT NULL, created_at > datetime('now', ?)
+		FROM messages
+		WHERE id = ?`,
+		fmt.Sprintf("-%d seconds", int64(editWindow/time.Second)), messageID).Scan(&sender, &content, &isPhoto, &deleted,
+		&withinWindow)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrMessageNotFound
+		return err
+	} else if err != nil {
+		return fmt.Errorf("failed to find message: %w", err)
+	}
+
+	// Step 2: Check the permissions and the edit window
+	switch {
+	case sender.String != currentUser:
+		err = ErrNotMessageSender
+	case deleted:
+		err = ErrMessageDeleted
+	case isPhoto:
+		err = ErrPhotoMessage
+	case !withinWindow:
+		err = ErrEditWindowExpired
+	case content == newContent:
+		err = ErrMessageUnchanged
+	}
+	if err != nil {
+		return err
+	}
+
+	// Step 3: Keep the current version in the history
+	_, err = tx.Exec(`
+		INSERT INTO message_edits (message_id, content, edited_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)`, messageID, content)
+	if err != nil {
+		return fmt.Errorf("failed to store previous version: %w", err)
+	}
+
+	// Step 4: Replace the content
+	_, err = tx.Exec(`
+		UPDATE messages
+		SET content = ?, edited_at = CURRENT_TIMESTAMP
+		WHERE id = ?`, newContent, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to update message: %w", err)
+	}
+
+	// Step 5: Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetMessageEdits returns the previous versions of a message, oldest first.
+// The list is empty if the message has never been edited.
+func (db *appdbimpl) GetMessageEdits(messageID int) ([]MessageEdit, error) {
+	rows, err := db.c.Query(`
+		SELECT content, edited_at
+		FROM message_edits
+		WHERE message_id = ?
+		ORDER BY id`, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying edits of message '%d': %w", messageID, err)
+	}
+	defer rows.Close()
+
+	edits := []MessageEdit{}
+	for rows.Next() {
+		var edit MessageEdit
+		if err := rows.Scan(&edit.Content, &edit.EditedAt); err != nil {
+			return nil, fmt.Errorf("error scanning message edit: %w", err)
+		}
+		edits = append(edits, edit)
+	}
+
+	// Check for errors while iterating through rows
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return edits, nil
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrNotForwardable is returned when forwarding a system message.
+var ErrNotForwardable = errors.New("system messages can't be forwarded")
+
+// ForwardMessage sends a copy of a message to each of the recipients (users or groups) in a single transaction: either
+// all the copies are sent, or none is. Only the participants of the conversation of the message can forward it.
+// The copies record the provenance of the message: forwarding a forwarded message keeps its original message and
+// sender, and increases the forward count.
+//
+// Parameters:
+// - currentUser: The user forwarding the message, who becomes the sender of the copies.
+// - messageID: The unique identifier of the message to forward.
+// - recipients: The users and groups to forward the message to.
+//
+// Returns:
+// - The IDs of the copies, in the order of `recipients`.
+// - ErrMessageNotFound if the message does not exist or the user is not part of its conversation, ErrNotForwardable
+// for system messages, ErrMessageDeleted for messages deleted for everyone, an error wrapping the error of
+// SendMessage for the first invalid recipient, or an error if the database operation fails.
+func (db *appdbimpl) ForwardMessage(currentUser string, messageID int, recipients []string) ([]int, error) {
+	tx, err := db.c.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	// Defer the rollback in case of any error, to ensure a clean up if something goes wrong
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
+
+	// Step 1: Retrieve the message, its provenance and its conversation
+	var content, kind string
+	var sender, user1, user2, groupname, forwardedFromSender sql.NullString
+	var forwardedFrom, forwardCount sql.NullInt64
+	var isPhoto, deleted bool
+	err = tx.QueryRow(`
+		SELECT m.content, m.sender, m.is_photo, m.kind, m.deleted_at IS NOT NULL,
+			m.forwarded_from, m.forwarded_from_sender, m.forward_count, c.user1, c.user2, c.groupname
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE m.id = ?`, messageID).Scan(&content, &sender, &isPhoto, &kind, &deleted,
+		&forwardedFrom, &forwardedFromSender, &forwardCount, &user1, &user2, &groupname)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrMessageNotFound
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find message: %w", err)
+	}
+
+	// Step 2: Only the participants of the conversation can see the message, the others must not learn it exists
+	if groupname.Valid {
+		_, err = groupRole(tx, groupname.String, currentUser)
+		if errors.Is(err, ErrNotGroupMember) || errors.Is(err, ErrGroupNotFound) {
+			err = ErrMessageNotFound
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
+	} else if user1.String != currentUser && user2.String != currentUser {
+		err = ErrMessageNotFound
+		return nil, err
+	}
+	if kind == MessageKindSystem {
+		err = ErrNotForwardable
+		return nil, err
+	}
+	if deleted {
+		err = ErrMessageDeleted
+		return nil, err
+	}
+
+	// Step 3: The copies keep the original message of a forwarded message
+	forward := &ForwardInfo{MessageID: messageID, Sender: sender.String, ForwardCount: 1}
+	if forwardedFrom.Valid {
+		forward = &ForwardInfo{
+			MessageID:    int(forwardedFrom.Int64),
+			Sender:       forwardedFromSender.String,
+			ForwardCount: int(forwardCount.Int64) + 1,
+		}
+	}
+
+	// Step 4: Send a copy to each recipient
+	messageIDs := make([]int, 0, len(recipients))
+	for _, recipient := range recipients {
+		var copyID int
+		copyID, err = sendMessageTx(tx, NewMessage{
+			FromUser: currentUser,
+			ToUser:   recipient,
+			Content:  content,
+			IsPhoto:  isPhoto,
+			Forward:  forward,
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to forward message to %s: %w", recipient, err)
+			return nil, err
+		}
+		messageIDs = append(messageIDs, copyID)
+	}
+
+	// Step 5: Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return messageIDs, nil
+}
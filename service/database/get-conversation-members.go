@@ -0,0 +1,76 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetConversationMembers returns the participants of the conversation `username` has with `partnerName`.
+// For a group, these are the current group members; for a 1:1 conversation, the two users.
+func (db *appdbimpl) GetConversationMembers(username, partnerName string) (*ConversationMembers, error) {
+	var isGroup bool
+	err := db.c.QueryRow(`SELECT COUNT(*) > 0 FROM groups WHERE groupname = ?`, partnerName).Scan(&isGroup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if partner is a group: %w", err)
+	}
+
+	if !isGroup {
+		return &ConversationMembers{Usernames: []string{username, partnerName}}, nil
+	}
+
+	members, err := db.getGroupMembers(partnerName)
+	if err != nil {
+		return nil, err
+	}
+	return &ConversationMembers{Groupname: partnerName, Usernames: members}, nil
+}
+
+// GetMessageConversationMembers returns the participants of the conversation the message belongs to.
+func (db *appdbimpl) GetMessageConversationMembers(messageID int) (*ConversationMembers, error) {
+	var user1, user2, groupname sql.NullString
+	err := db.c.QueryRow(`
+		SELECT c.user1, c.user2, c.groupname
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE m.id = ?`, messageID).Scan(&user1, &user2, &groupname)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find conversation of message %d: %w", messageID, err)
+	}
+
+	if !groupname.Valid {
+		return &ConversationMembers{Usernames: []string{user1.String, user2.String}}, nil
+	}
+
+	members, err := db.getGroupMembers(groupname.String)
+	if err != nil {
+		return nil, err
+	}
+	return &ConversationMembers{Groupname: groupname.String, Usernames: members}, nil
+}
+
+// Helper function: Retrieve the usernames of all members of a group
+func (db *appdbimpl) getGroupMembers(groupname string) ([]string, error) {
+	rows, err := db.c.Query(`SELECT membername FROM group_members WHERE groupname = ?`, groupname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query members of group '%s': %w", groupname, err)
+	}
+	defer rows.Close()
+
+	var members []string
+	for rows.Next() {
+		var member string
+		if err := rows.Scan(&member); err != nil {
+			return nil, fmt.Errorf("error scanning group member: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return members, nil
+}
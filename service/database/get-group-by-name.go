@@ -10,7 +10,7 @@ var ErrGroupNotFound = errors.New("group not found")
 // GetGroupByName retrieves a group by its name from the database.
 // Returns: Group struct if found, otherwise an error.
 func (db *appdbimpl) GetGroupByName(groupName string) (*Group, error) {
-	query := "SELECT groupname, group_photo_url FROM groups WHERE groupname = ?"
+	query := "SELECT groupname, COALESCE(group_photo_url, '') FROM groups WHERE groupname = ?"
 	var group Group
 
 	// Execute query and scan result into the group struct
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Roles of the members of a group. The owner has every right of an admin.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+var (
+	ErrNotGroupMember = errors.New("user is not a member of the group")
+	ErrNotGroupAdmin  = errors.New("only group admins can do this")
+	ErrNotGroupOwner  = errors.New("only the group owner can do this")
+	ErrMemberNotFound = errors.New("member not found in the group")
+)
+
+// rowQuerier is implemented by both *sql.DB and *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// groupRole returns the role of a user in a group.
+// It returns ErrGroupNotFound if the group does not exist, and ErrNotGroupMember if the user is not a member of it.
+func groupRole(q rowQuerier, groupname, username string) (string, error) {
+	var role sql.NullString
+	err := q.QueryRow(`
+		SELECT gm.role
+		FROM groups g
+		LEFT JOIN group_members gm ON gm.groupname = g.groupname AND gm.membername = ?
+		WHERE g.groupname = ?`, username, groupname).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrGroupNotFound
+	} else if err != nil {
+		return "", fmt.Errorf("failed to retrieve group role: %w", err)
+	}
+	if !role.Valid {
+		return "", ErrNotGroupMember
+	}
+	return role.String, nil
+}
+
+// targetRole returns the role of the member targeted by a moderation action, ErrMemberNotFound if they are not part
+// of the group.
+func targetRole(q rowQuerier, groupname, member string) (string, error) {
+	role, err := groupRole(q, groupname, member)
+	if errors.Is(err, ErrNotGroupMember) {
+		return "", ErrMemberNotFound
+	}
+	return role, err
+}
+
+// requireGroupAdmin checks that the user is the owner or an admin of the group.
+func requireGroupAdmin(q rowQuerier, groupname, username string) error {
+	role, err := groupRole(q, groupname, username)
+	if err != nil {
+		return err
+	}
+	if role != RoleOwner && role != RoleAdmin {
+		return ErrNotGroupAdmin
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrMediaNotFound is returned when an uploaded file is not in the `media` table.
+var ErrMediaNotFound = errors.New("media not found")
+
+// GetMedia returns the record of an uploaded file by its storage name.
+//
+// Returns:
+// - The media record, or ErrMediaNotFound if no file with that name was uploaded.
+func (db *appdbimpl) GetMedia(name string) (*Media, error) {
+	var m Media
+	err := db.c.QueryRow(`
+		SELECT name, owner, size, mime_type, refs, COALESCE(variant_of, ''), created_at, deleting_at
+		FROM media
+		WHERE name = ?`, name).Scan(&m.Name, &m.Owner, &m.Size, &m.MimeType, &m.Refs, &m.VariantOf, &m.CreatedAt,
+		&m.DeletingAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMediaNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading media %q: %w", name, err)
+	}
+	return &m, nil
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// GetMessageReceipts returns the delivery and read status of a message for each of its recipients, ordered by
+// username.
+func (db *appdbimpl) GetMessageReceipts(messageID int) ([]Receipt, error) {
+	rows, err := db.c.Query(`
+		SELECT user_id, received_at, read_at
+		FROM message_status
+		WHERE message_id = ?
+		ORDER BY user_id`, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying receipts of message '%d': %w", messageID, err)
+	}
+	defer rows.Close()
+
+	receipts := []Receipt{}
+	for rows.Next() {
+		var receipt Receipt
+		var receivedAt, readAt sql.NullTime
+		if err := rows.Scan(&receipt.Username, &receivedAt, &readAt); err != nil {
+			return nil, fmt.Errorf("error scanning receipt: %w", err)
+		}
+		receipt.Received, receipt.ReceivedAt = receivedAt.Valid, statusTime(receivedAt)
+		receipt.Read, receipt.ReadAt = readAt.Valid, statusTime(readAt)
+		receipts = append(receipts, receipt)
+	}
+
+	// Check for errors while iterating through rows
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return receipts, nil
+}
@@ -1,6 +1,12 @@
 package database
 
-import "fmt"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrMessageNotFound = errors.New("message not found")
 
 // GetMessage retrieves a specific message by its ID or the latest message in a conversation.
 //
@@ -12,37 +18,27 @@ import "fmt"
 //
 // Parameters:
 // - messageID: (optional) The ID of the message to fetch. If nil, the latest message is retrieved.
-// - username: The username of the requesting user.
-// - partner: The username of the conversation partner (or group name).
+// - currentUser: The username of the requesting user, whose own reactions are flagged as such.
 //
 // Returns:
 // - A pointer to a ConversationDetail struct containing message details.
 // - An error if the message retrieval fails.
-func (db *appdbimpl) GetMessage(messageID *int) (*ConversationDetail, error) {
-	var msg ConversationDetail
-	var err error
-
+func (db *appdbimpl) GetMessage(messageID *int, currentUser string) (*ConversationDetail, error) {
 	// If a specific message ID is provided, retrieve that message
-	err = db.c.QueryRow(`
-    SELECT 
-        m.id, 
-        m.content,
-        m.sender, 
-        m.is_photo, 
-        m.is_forwarded, 
-        m.created_at, 
-        (SELECT COUNT(*) FROM message_status WHERE message_id = m.id AND received = FALSE) = 0 AS fully_received,
-        (SELECT COUNT(*) FROM message_status WHERE message_id = m.id AND read = FALSE) = 0 AS fully_read
-    FROM messages m
-    WHERE m.id = ?`, *messageID).Scan(&msg.MessageID, &msg.Content, &msg.Sender, &msg.IsPhoto, &msg.IsForwarded, &msg.Timestamp, &msg.FullyReceived, &msg.FullyRead)
+	msg, err := scanConversationDetail(db.c.QueryRow(`
+    SELECT `+messageColumns+`
+    FROM `+messageTables+`
+    WHERE m.id = ?`, *messageID))
 
 	// Handle any errors during the database query
-	if err != nil {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	} else if err != nil {
 		return nil, fmt.Errorf("error retrieving message: %w", err)
 	}
 
 	// Retrieve reactions for the message
-	msg.Reactions, err = db.getReactionsForMessage(msg.MessageID)
+	msg.Reactions, err = db.getReactionsForMessage(msg.MessageID, currentUser)
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving reactions: %w", err)
 	}
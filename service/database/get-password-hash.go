@@ -0,0 +1,39 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetPasswordHash returns the encoded password hash of the user, or an empty string if the user has no password yet.
+// Returns ErrUserNotFound if the user does not exist.
+func (db *appdbimpl) GetPasswordHash(username string) (string, error) {
+	var passwordHash sql.NullString
+	err := db.c.QueryRow(`SELECT password_hash FROM users WHERE username = ?`, username).Scan(&passwordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read password of user '%s': %w", username, err)
+	}
+
+	return passwordHash.String, nil
+}
+
+// SetPasswordHash stores the encoded password hash of an existing user.
+func (db *appdbimpl) SetPasswordHash(username string, passwordHash string) error {
+	res, err := db.c.Exec(`UPDATE users SET password_hash = ? WHERE username = ?`, passwordHash, username)
+	if err != nil {
+		return fmt.Errorf("failed to set password of user '%s': %w", username, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
@@ -5,22 +5,37 @@ import (
 	"errors"
 )
 
-// GetUsernameByToken checks if the token exists and returns the associated username.
-// If the token does not exist, it returns an empty string and no error.
+// GetUsernameByToken checks if the token belongs to a valid (not expired) session and returns the associated username.
+// If the token does not exist or the session expired, it returns an empty string and no error.
 // If there is a database error, it returns the error.
 func (db *appdbimpl) GetUsernameByToken(token string) (string, error) {
 	var username string
+	var stale bool
+	tokenHash := hashToken(token)
+
 	// SQL query to find the username associated with the provided token.
-	query := `SELECT username FROM users WHERE auth_token = ?`
-	err := db.c.QueryRow(query, token).Scan(&username)
+	query := `
+		SELECT username, last_used_at < datetime('now', '-1 minute')
+		FROM sessions
+		WHERE token_hash = ? AND expires_at > CURRENT_TIMESTAMP`
+	err := db.c.QueryRow(query, tokenHash).Scan(&username, &stale)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			// No user found with this token; return an empty username and no error.
+			// No valid session with this token; return an empty username and no error.
 			return "", nil
 		}
 		// Some other database error occurred; return the error.
 		return "", err
 	}
+
+	// Record the usage, at most once a minute to avoid a write on every request.
+	if stale {
+		_, err = db.c.Exec(`UPDATE sessions SET last_used_at = CURRENT_TIMESTAMP WHERE token_hash = ?`, tokenHash)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	// Username successfully found; return it.
 	return username, nil
 }
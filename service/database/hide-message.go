@@ -0,0 +1,43 @@
+package database
+
+import (
+	"fmt"
+)
+
+// HideMessage deletes a message for a single user: it is hidden from the user's view of the conversation, from the
+// preview of the conversation and from the user's searches, and stays visible to the other participants. Hiding a
+// message already hidden has no effect.
+//
+// Parameters:
+// - username: The user hiding the message, who must be a participant of its conversation.
+// - messageID: The unique identifier of the message to hide.
+//
+// Returns:
+// - ErrMessageNotFound if the message does not exist, or an error if the database operation fails.
+func (db *appdbimpl) HideMessage(username string, messageID int) error {
+	res, err := db.c.Exec(`
+		INSERT INTO hidden_messages (username, message_id)
+		SELECT ?, id FROM messages WHERE id = ?
+		ON CONFLICT (username, message_id) DO NOTHING`, username, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to hide message: %w", err)
+	}
+
+	// Nothing is inserted for a missing message, and for a message already hidden
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		var exists bool
+		err = db.c.QueryRow(`SELECT COUNT(*) > 0 FROM messages WHERE id = ?`, messageID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to find message: %w", err)
+		}
+		if !exists {
+			return ErrMessageNotFound
+		}
+	}
+
+	return nil
+}
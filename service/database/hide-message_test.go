@@ -0,0 +1,68 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestHideMessage(t *testing.T) {
+	db := newTestDB(t)
+	createUsers(t, db, "alice", "bob")
+	first := sendMessage(t, db, "alice", "bob", "hello bob")
+	second := sendMessage(t, db, "bob", "alice", "hello alice")
+
+	if err := db.HideMessage("bob", second); err != nil {
+		t.Fatalf("HideMessage: %v", err)
+	}
+	if err := db.HideMessage("bob", second); err != nil {
+		t.Errorf("HideMessage again: %v", err)
+	}
+	if err := db.HideMessage("bob", 1000); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("HideMessage of a missing message: got %v, want ErrMessageNotFound", err)
+	}
+
+	// Only bob no longer sees the message, in the conversation, its preview and the search
+	assertVisible := func(username, partner string, want []int, wantPreview string) {
+		t.Helper()
+		page, err := db.ShowConversation(username, partner, PageRequest{Limit: 10})
+		if err != nil {
+			t.Fatalf("ShowConversation(%s): %v", username, err)
+		}
+		if got := messageIDs(page.Messages); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s sees messages %v, want %v", username, got, want)
+		}
+		previews, err := db.LoadUserConversations(username, false)
+		if err != nil {
+			t.Fatalf("LoadUserConversations(%s): %v", username, err)
+		}
+		if len(previews) != 1 || previews[0].LastMessage.String != wantPreview {
+			t.Errorf("%s sees previews %+v, want last message %q", username, previews, wantPreview)
+		}
+		if !db.SearchAvailable() {
+			return
+		}
+		hits, _, err := db.SearchMessages(username, "hello", 10, 0)
+		if err != nil {
+			t.Fatalf("SearchMessages(%s): %v", username, err)
+		}
+		found := make([]int, len(hits))
+		for i, hit := range hits {
+			found[i] = hit.MessageID
+		}
+		// Hits are ranked by relevance
+		sort.Sort(sort.Reverse(sort.IntSlice(found)))
+		if fmt.Sprint(found) != fmt.Sprint(want) {
+			t.Errorf("%s finds messages %v, want %v", username, found, want)
+		}
+	}
+	assertVisible("bob", "alice", []int{first}, "hello bob")
+	assertVisible("alice", "bob", []int{second, first}, "hello alice")
+
+	// Hidden messages stay hidden when their user changes username
+	if err := db.ChangeUsername("bob", "robert"); err != nil {
+		t.Fatalf("ChangeUsername: %v", err)
+	}
+	assertVisible("robert", "alice", []int{first}, "hello bob")
+}
@@ -0,0 +1,19 @@
+package database
+
+import "fmt"
+
+// IsPublicMedia reports whether an uploaded file is public: it is public while it is the profile picture of a user or
+// the picture of a group. Files only sent in chats are private to the participants of the conversations.
+func (db *appdbimpl) IsPublicMedia(name string) (bool, error) {
+	var public bool
+	err := db.c.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM users WHERE substr(profile_photo_url, -(length(?1) + 9)) = '/uploads/' || ?1
+		) OR EXISTS (
+			SELECT 1 FROM groups WHERE substr(group_photo_url, -(length(?1) + 9)) = '/uploads/' || ?1
+		)`, name).Scan(&public)
+	if err != nil {
+		return false, fmt.Errorf("error checking whether media %q is public: %w", name, err)
+	}
+	return public, nil
+}
@@ -1,10 +1,14 @@
 package database
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 )
 
 // LeaveGroup removes a user from an existing group.
+// If the owner leaves, the ownership passes to the longest-standing admin, or to the longest-standing member if the
+// group has no admins. Both are recorded with system messages in the group conversation.
 //
 // Parameters:
 // - groupName: The name of the group that the user wants to leave.
@@ -13,48 +17,77 @@ import (
 // Returns:
 // - error: If an error occurs during the process (e.g., group does not exist, user is not a member, or failure to remove user), an error is returned.
 func (db *appdbimpl) LeaveGroup(groupName string, currentUser string) error {
-	// Check if the group exists
-	var groupExists int
-	err := db.c.QueryRow(`
-		SELECT COUNT(*) 
-		FROM groups 
-		WHERE groupname = ?;
-	`, groupName).Scan(&groupExists)
-
+	tx, err := db.c.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to check if group exists: %w", err)
+		return fmt.Errorf("failed to start transaction: %w", err)
 	}
 
-	// If the group does not exist, return an error
-	if groupExists == 0 {
-		return fmt.Errorf("group with name '%s' does not exist", groupName)
-	}
+	// Defer the rollback in case of any error, to ensure a clean up if something goes wrong
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
 
-	// Check if the user is a member of the group
-	var userIsMember int
-	err = db.c.QueryRow(`
-		SELECT COUNT(*) 
-		FROM group_members 
-		WHERE groupname = ? AND membername = ?;
-	`, groupName, currentUser).Scan(&userIsMember)
+	// Check that the group exists and the user is a member of it
+	role, err := groupRole(tx, groupName, currentUser)
+	if errors.Is(err, ErrGroupNotFound) {
+		return fmt.Errorf("group with name '%s' does not exist: %w", groupName, err)
+	} else if errors.Is(err, ErrNotGroupMember) {
+		return fmt.Errorf("user '%s' is not a member of group '%s': %w", currentUser, groupName, err)
+	} else if err != nil {
+		return err
+	}
 
+	// Remove the user from the group
+	err = deleteGroupMember(tx, groupName, currentUser)
 	if err != nil {
-		return fmt.Errorf("failed to check if user is a member of the group: %w", err)
+		return err
 	}
 
-	// If the user is not a member of the group, return an error
-	if userIsMember == 0 {
-		return fmt.Errorf("user '%s' is not a member of group '%s'", currentUser, groupName)
+	// Record the departure in the group conversation
+	_, err = insertGroupSystemMessage(tx, groupName, SystemEvent{Type: SystemMemberLeft, Actor: currentUser})
+	if err != nil {
+		return err
 	}
 
-	// Remove the user from the group
-	_, err = db.c.Exec(`
-		DELETE FROM group_members 
-		WHERE groupname = ? AND membername = ?;
-	`, groupName, currentUser)
+	// Hand the ownership over to the next member in line, unless the group is now empty
+	if role == RoleOwner {
+		var newOwner string
+		err = tx.QueryRow(`
+			UPDATE group_members
+			SET role = ?
+			WHERE rowid = (
+				SELECT rowid FROM group_members
+				WHERE groupname = ?
+				ORDER BY role = ? DESC, rowid
+				LIMIT 1
+			)
+			RETURNING membername`, RoleOwner, groupName, RoleAdmin).Scan(&newOwner)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		} else if err != nil {
+			return fmt.Errorf("failed to transfer group ownership: %w", err)
+		} else {
+			_, err = insertGroupSystemMessage(tx, groupName, SystemEvent{
+				Type:   SystemRoleChanged,
+				Actor:  currentUser,
+				Member: newOwner,
+				Role:   RoleOwner,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
 
+	// Commit the transaction
+	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("failed to remove user from group: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	// Return nil if the user was successfully removed from the group
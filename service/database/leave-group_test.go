@@ -0,0 +1,46 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLeaveGroupPassesOwnership(t *testing.T) {
+	db := newGroupDB(t)
+
+	// Without admins, the longest-standing member becomes the owner
+	if err := db.LeaveGroup("friends", "alice"); err != nil {
+		t.Fatalf("LeaveGroup: %v", err)
+	}
+	want := map[string]string{"bob": RoleOwner, "carol": RoleMember, "dave": RoleMember}
+	if got := groupRoles(t, db, "friends"); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("roles %v, want %v", got, want)
+	}
+	event := lastSystemEvent(t, db, "bob", "friends")
+	if event.Type != SystemRoleChanged || event.Member != "bob" || event.Role != RoleOwner {
+		t.Errorf("system event %+v, want bob becoming owner", event)
+	}
+
+	// Admins come before the longest-standing members
+	if err := db.SetGroupRole("bob", "friends", "dave", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.LeaveGroup("friends", "bob"); err != nil {
+		t.Fatalf("LeaveGroup: %v", err)
+	}
+	want = map[string]string{"carol": RoleMember, "dave": RoleOwner}
+	if got := groupRoles(t, db, "friends"); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("roles %v, want %v", got, want)
+	}
+
+	// Members leaving don't change the owner
+	if err := db.LeaveGroup("friends", "carol"); err != nil {
+		t.Fatalf("LeaveGroup: %v", err)
+	}
+	if err := db.LeaveGroup("friends", "dave"); err != nil {
+		t.Fatalf("LeaveGroup of the last member: %v", err)
+	}
+	if got := groupRoles(t, db, "friends"); len(got) != 0 {
+		t.Errorf("roles %v, want an empty group", got)
+	}
+}
@@ -0,0 +1,33 @@
+package database
+
+import "fmt"
+
+// ListGroupMembers returns the members of a group with their role, the owner first, then the admins, then the other
+// members, each in order of joining.
+func (db *appdbimpl) ListGroupMembers(groupname string) ([]GroupMember, error) {
+	rows, err := db.c.Query(`
+		SELECT membername, role
+		FROM group_members
+		WHERE groupname = ?
+		ORDER BY CASE role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, rowid`, groupname)
+	if err != nil {
+		return nil, fmt.Errorf("error querying members of group '%s': %w", groupname, err)
+	}
+	defer rows.Close()
+
+	members := []GroupMember{}
+	for rows.Next() {
+		var member GroupMember
+		if err := rows.Scan(&member.Username, &member.Role); err != nil {
+			return nil, fmt.Errorf("error scanning group member: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	// Check for errors while iterating through rows
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+
+	return members, nil
+}
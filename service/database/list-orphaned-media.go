@@ -0,0 +1,53 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// orphanedMediaCondition matches the originals (alias `o`) that, together with all their variants, are unreferenced
+// since at least the grace period passed as parameter (a datetime modifier like "-3600 seconds").
+const orphanedMediaCondition = `
+	o.variant_of IS NULL
+	AND NOT EXISTS (
+		SELECT 1
+		FROM media f
+		WHERE (f.name = o.name OR f.variant_of = o.name)
+		AND (f.refs > 0 OR f.unreferenced_at > datetime('now', ?))
+	)`
+
+// ListOrphanedMedia returns the names of up to `limit` uploaded images that are not used anywhere, and whose variants
+// are not used either, since at least `gracePeriod`.
+//
+// Returns:
+// - The names of the originals that can be removed with MarkOrphanedMedia, or an error if the query fails.
+func (db *appdbimpl) ListOrphanedMedia(gracePeriod time.Duration, limit int) ([]string, error) {
+	rows, err := db.c.Query(`
+		SELECT o.name
+		FROM media o
+		WHERE`+orphanedMediaCondition+`
+		ORDER BY o.unreferenced_at
+		LIMIT ?`, graceModifier(gracePeriod), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing orphaned media: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error reading orphaned media: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing orphaned media: %w", err)
+	}
+	return names, nil
+}
+
+// graceModifier returns the SQLite datetime modifier going back by `gracePeriod`.
+func graceModifier(gracePeriod time.Duration) string {
+	return fmt.Sprintf("-%d seconds", int64(gracePeriod/time.Second))
+}
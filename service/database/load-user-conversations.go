@@ -1,11 +1,52 @@
 package database
 
-import "time"
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DavideStummSapienza/WASAText/service/globaltime"
+)
 
 // LoadUserConversations fetches a list of conversation previews for the given user.
-func (db *appdbimpl) LoadUserConversations(username string) ([]ConversationPreview, error) {
+//
+// The previews are computed in a single query: the conversations of the user are selected once, and their last
+// messages, the number of messages still unread by the user and the pending reads of the last messages are each
+// aggregated over all those conversations at once. The messages the user deleted for themselves are left out, and a
+// last message deleted for everyone is previewed as such.
+//
+// The conversations pinned by the user come first, in their pin order, followed by the others, most recent first.
+// Archived conversations are only listed if `includeArchived` is set.
+func (db *appdbimpl) LoadUserConversations(username string, includeArchived bool) ([]ConversationPreview, error) {
 	// SQL query to retrieve the required data for the conversation preview
 	query := `
+	WITH mine AS (
+		SELECT c.id
+		FROM conversations c
+		LEFT JOIN group_members gm ON gm.groupname = c.groupname AND gm.membername = ?
+		WHERE c.user1 = ? OR c.user2 = ? OR gm.membername IS NOT NULL
+	),
+	ranked AS (
+		SELECT m.id, m.conversation_id,
+			ROW_NUMBER() OVER (PARTITION BY m.conversation_id ORDER BY m.created_at DESC, m.id DESC) AS rank
+		FROM messages m
+		WHERE m.conversation_id IN (SELECT id FROM mine)
+		AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.username = ?)
+	),
+	unread AS (
+		SELECT m.conversation_id, COUNT(*) AS count
+		FROM message_status s
+		JOIN messages m ON m.id = s.message_id
+		WHERE s.user_id = ? AND s.read_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.username = s.user_id)
+		GROUP BY m.conversation_id
+	),
+	pending AS (
+		SELECT s.message_id, COUNT(*) AS count
+		FROM message_status s
+		JOIN ranked r ON r.id = s.message_id AND r.rank = 1
+		WHERE s.read_at IS NULL
+		GROUP BY s.message_id
+	)
 	SELECT 
 		CASE 
 			WHEN c.groupname IS NOT NULL THEN g.groupname
@@ -24,32 +65,39 @@ func (db *appdbimpl) LoadUserConversations(username string) ([]ConversationPrevi
 				END
 		END AS photo_url,
 		CASE
+			WHEN m.deleted_at IS NOT NULL THEN 'Message deleted'
 			WHEN m.is_photo THEN 'Photo'
 			WHEN m.content IS NULL THEN 'No messages yet'
         	ELSE m.content
 		END AS last_message,
 		m.created_at AS last_message_time,
+		COALESCE(m.sender, '') AS last_message_sender,
+		p.count IS NULL AS last_message_fully_read,
+		COALESCE(un.count, 0) AS unread_count,
 		CASE 
     		WHEN c.groupname IS NOT NULL THEN true
     		ELSE false
-		END AS is_group
-	FROM conversations c
-	LEFT JOIN messages m ON m.id = (
-		SELECT id FROM messages 
-		WHERE conversation_id = c.id 
-		ORDER BY created_at DESC 
-		LIMIT 1
-	)
+		END AS is_group,
+		COALESCE(cs.archived, FALSE) AS archived,
+		cs.muted_until,
+		cs.pin_order
+	FROM mine
+	JOIN conversations c ON c.id = mine.id
+	LEFT JOIN conversation_settings cs ON cs.conversation_id = c.id AND cs.username = ?
+	LEFT JOIN ranked r ON r.conversation_id = c.id AND r.rank = 1
+	LEFT JOIN messages m ON m.id = r.id
+	LEFT JOIN pending p ON p.message_id = m.id
+	LEFT JOIN unread un ON un.conversation_id = c.id
 	LEFT JOIN users u1 ON u1.username = c.user1
 	LEFT JOIN users u2 ON u2.username = c.user2
 	LEFT JOIN groups g ON g.groupname = c.groupname
-	LEFT JOIN group_members gm ON gm.groupname = c.groupname
-	WHERE c.user1 = ? OR c.user2 = ? OR gm.membername = ?
-	ORDER BY m.created_at DESC;
+	WHERE ? OR NOT COALESCE(cs.archived, FALSE)
+	ORDER BY cs.pin_order IS NULL, cs.pin_order, m.created_at DESC;
 	`
 
 	// Execute the query
-	rows, err := db.c.Query(query, username, username, username, username, username)
+	rows, err := db.c.Query(query, username, username, username, username, username, username, username, username,
+		includeArchived)
 	if err != nil {
 		return nil, err
 	}
@@ -59,11 +107,24 @@ func (db *appdbimpl) LoadUserConversations(username string) ([]ConversationPrevi
 	var previews []ConversationPreview
 	for rows.Next() {
 		var preview ConversationPreview
-		err := rows.Scan(&preview.Name, &preview.PhotoURL, &preview.LastMessage, &preview.LastMessageTime, &preview.IsGroup)
+		var mutedUntil sql.NullTime
+		var pinOrder sql.NullInt64
+		err := rows.Scan(&preview.Name, &preview.PhotoURL, &preview.LastMessage, &preview.LastMessageTime,
+			&preview.LastMessageSender, &preview.LastMessageFullyRead, &preview.UnreadCount, &preview.IsGroup,
+			&preview.Archived, &mutedUntil, &pinOrder)
 		if err != nil {
 			return nil, err
 		}
 
+		// A mute that has expired is no mute
+		if mutedUntil.Valid && mutedUntil.Time.After(globaltime.Now()) {
+			preview.MutedUntil = &mutedUntil.Time
+		}
+		if pinOrder.Valid {
+			order := int(pinOrder.Int64)
+			preview.PinOrder = &order
+		}
+
 		if !preview.LastMessageTime.Valid {
 			preview.LastMessageTime.Time = time.Time{} // Default "zero time"
 		}
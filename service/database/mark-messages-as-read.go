@@ -0,0 +1,17 @@
+package database
+
+// MarkMessagesAsRead marks the messages of a conversation as read by a user, recording when they were read.
+// If `upTo` is not 0, only the messages up to that one (included) are marked.
+// Messages read without having been marked as received are marked as received at the same time.
+//
+// Returns:
+// - The number of messages newly marked as read.
+// - ErrMessageNotInConversation if `upTo` is not a message of the conversation, or an error if the update fails.
+func (db *appdbimpl) MarkMessagesAsRead(username string, partnerUsername string, upTo int) (int, error) {
+	return db.markMessages(username, partnerUsername, upTo, `
+		UPDATE message_status
+		SET read_at = CURRENT_TIMESTAMP, received_at = COALESCE(received_at, CURRENT_TIMESTAMP)
+		WHERE user_id = ?
+		AND read_at IS NULL
+		AND message_id IN (`+messagesUpTo+`)`)
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ErrMessageNotInConversation is returned when the message given as `upTo` is not part of the conversation.
+var ErrMessageNotInConversation = errors.New("message is not part of the conversation")
+
+// MarkMessagesAsReceived marks the messages of a conversation as received by a user, recording when they were
+// received. If `upTo` is not 0, only the messages up to that one (included) are marked.
+//
+// Returns:
+// - The number of messages newly marked as received.
+// - ErrMessageNotInConversation if `upTo` is not a message of the conversation, or an error if the update fails.
+func (db *appdbimpl) MarkMessagesAsReceived(username string, partnerUsername string, upTo int) (int, error) {
+	return db.markMessages(username, partnerUsername, upTo, `
+		UPDATE message_status
+		SET received_at = CURRENT_TIMESTAMP
+		WHERE user_id = ?
+		AND received_at IS NULL
+		AND message_id IN (`+messagesUpTo+`)`)
+}
+
+// messagesUpTo selects the ids of the messages of a conversation up to a given message, or all of them if that is 0.
+// Its parameters are the conversation id, then the message id twice.
+const messagesUpTo = `
+			SELECT m.id
+			FROM messages m
+			WHERE m.conversation_id = ?
+			AND (? = 0 OR (m.created_at, m.id) <= (SELECT created_at, id FROM messages WHERE id = ?))`
+
+// markMessages runs an update of the statuses of `username` selected with messagesUpTo, and returns the number of
+// updated statuses.
+func (db *appdbimpl) markMessages(username string, partnerUsername string, upTo int, query string) (int, error) {
+	// Find the conversation
+	conversationID, err := db.findConversationID(username, partnerUsername)
+	if errors.Is(err, sql.ErrNoRows) {
+		// No conversation yet, so there is nothing to mark
+		if upTo != 0 {
+			return 0, ErrMessageNotInConversation
+		}
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("error finding conversation '%s': %w", partnerUsername, err)
+	}
+
+	// Check that the limit is a message of this conversation
+	if upTo != 0 {
+		var exists bool
+		err = db.c.QueryRow(`SELECT COUNT(*) > 0 FROM messages WHERE id = ? AND conversation_id = ?`, upTo, conversationID).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("error checking message: %w", err)
+		}
+		if !exists {
+			return 0, ErrMessageNotInConversation
+		}
+	}
+
+	res, err := db.c.Exec(query, username, conversationID, upTo, upTo)
+	if err != nil {
+		log.Printf("Error updating message status for user %s and partner %s: %v", username, partnerUsername, err)
+		return 0, fmt.Errorf("error updating message status: %w", err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error counting updated statuses: %w", err)
+	}
+	return int(updated), nil
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// MarkOrphanedMedia marks the records of an uploaded image and of its variants as being deleted, if they are all still
+// unreferenced since at least `gracePeriod`. The check is repeated here because the image may have been used again
+// after it was listed by ListOrphanedMedia.
+//
+// The records are kept while the caller removes the files from the media storage, and then deletes each record with
+// DeleteMarkedMedia, or unmarks it with UnmarkMedia if the file couldn't be removed.
+//
+// Returns:
+// - The marked records, variants first (none if the image is used again), or an error if the update fails.
+func (db *appdbimpl) MarkOrphanedMedia(name string, gracePeriod time.Duration) ([]Media, error) {
+	tx, err := db.c.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	// Defer the rollback in case of any error, to ensure a clean up if something goes wrong
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
+
+	// Step 1: Read the records of the image and of its variants, if they are all orphaned
+	rows, err := tx.Query(`
+		SELECT m.name, m.owner, m.size, m.mime_type, m.refs, COALESCE(m.variant_of, ''), m.created_at
+		FROM media m
+		JOIN media o ON o.name = ?
+		WHERE (m.name = o.name OR m.variant_of = o.name) AND`+orphanedMediaCondition+`
+		ORDER BY m.variant_of IS NULL, m.name`,
+		name, graceModifier(gracePeriod))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read orphaned media: %w", err)
+	}
+	var marked []Media
+	for rows.Next() {
+		var m Media
+		err = rows.Scan(&m.Name, &m.Owner, &m.Size, &m.MimeType, &m.Refs, &m.VariantOf, &m.CreatedAt)
+		if err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to read orphaned media: %w", err)
+		}
+		marked = append(marked, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read orphaned media: %w", err)
+	}
+	if len(marked) == 0 {
+		// The image is used again, or it was already removed
+		_ = tx.Rollback()
+		return nil, nil
+	}
+
+	// Step 2: Mark the records
+	_, err = tx.Exec(`UPDATE media SET deleting_at = CURRENT_TIMESTAMP WHERE name = ? OR variant_of = ?`, name, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark media: %w", err)
+	}
+
+	// Step 3: Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return marked, nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newMediaDB returns a database with an orphaned image "a.png", unreferenced for a day, and its variant "a-preview.png".
+func newMediaDB(t *testing.T) *appdbimpl {
+	t.Helper()
+	db := newTestDB(t)
+	createUsers(t, db, "alice")
+	for _, m := range []Media{
+		{Name: "a.png", Owner: "alice", Size: 10, MimeType: "image/png"},
+		{Name: "a-preview.png", Owner: "alice", Size: 5, MimeType: "image/png", VariantOf: "a.png"},
+	} {
+		if err := db.CreateMedia(m); err != nil {
+			t.Fatalf("CreateMedia(%s): %v", m.Name, err)
+		}
+	}
+	exec(t, db.c, `UPDATE media SET unreferenced_at = datetime('now', '-1 day')`)
+	return db
+}
+
+// deleting reports whether the record of a file is marked as being deleted, failing the test if it doesn't exist.
+func deleting(t *testing.T, db *appdbimpl, name string) bool {
+	t.Helper()
+	m, err := db.GetMedia(name)
+	if err != nil {
+		t.Fatalf("GetMedia(%s): %v", name, err)
+	}
+	return m.DeletingAt != nil
+}
+
+func TestMarkOrphanedMedia(t *testing.T) {
+	db := newMediaDB(t)
+
+	marked, err := db.MarkOrphanedMedia("a.png", time.Hour)
+	if err != nil {
+		t.Fatalf("MarkOrphanedMedia: %v", err)
+	}
+	names := make([]string, len(marked))
+	for i, m := range marked {
+		names[i] = m.Name
+	}
+	if want := []string{"a-preview.png", "a.png"}; fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("marked %v, want %v", names, want)
+	}
+	if !deleting(t, db, "a.png") || !deleting(t, db, "a-preview.png") {
+		t.Error("the records are not marked")
+	}
+
+	// An upload of the same file meanwhile doesn't clear the mark: the file is still being removed
+	if err := db.CreateMedia(Media{Name: "a.png", Owner: "alice", Size: 10, MimeType: "image/png"}); err != nil {
+		t.Fatal(err)
+	}
+	if !deleting(t, db, "a.png") {
+		t.Error("CreateMedia cleared the mark")
+	}
+
+	// A record unmarked when the file is kept is not deleted
+	if err := db.UnmarkMedia("a.png"); err != nil {
+		t.Fatalf("UnmarkMedia: %v", err)
+	}
+	if err := db.DeleteMarkedMedia("a.png"); err != nil {
+		t.Fatalf("DeleteMarkedMedia: %v", err)
+	}
+	if deleting(t, db, "a.png") {
+		t.Error("UnmarkMedia kept the mark")
+	}
+
+	if err := db.DeleteMarkedMedia("a-preview.png"); err != nil {
+		t.Fatalf("DeleteMarkedMedia: %v", err)
+	}
+	if _, err := db.GetMedia("a-preview.png"); !errors.Is(err, ErrMediaNotFound) {
+		t.Errorf("GetMedia of a deleted record: got %v, want ErrMediaNotFound", err)
+	}
+}
+
+func TestMarkOrphanedMediaSkipsUsedImages(t *testing.T) {
+	tests := []struct {
+		name   string
+		update string
+	}{
+		{"referenced variant", `UPDATE media SET refs = 1 WHERE name = 'a-preview.png'`},
+		{"uploaded again", `UPDATE media SET unreferenced_at = CURRENT_TIMESTAMP WHERE name = 'a.png'`},
+	}
+	for _, tt := range tests {
+		db := newMediaDB(t)
+		exec(t, db.c, tt.update)
+
+		marked, err := db.MarkOrphanedMedia("a.png", time.Hour)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(marked) != 0 || deleting(t, db, "a.png") || deleting(t, db, "a-preview.png") {
+			t.Errorf("%s: marked %+v", tt.name, marked)
+		}
+	}
+}
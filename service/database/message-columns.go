@@ -0,0 +1,113 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// replySnippetLength is the maximum number of characters of the quoted preview of a reply.
+const replySnippetLength = 100
+
+// messageColumns is the select list of a ConversationDetail, read from messageTables.
+// Rows selected with it are read with scanConversationDetail.
+const messageColumns = `
+        m.id, 
+        m.content,
+        COALESCE(m.sender, ''), 
+        m.is_photo, 
+        m.is_forwarded, 
+        m.created_at,
+        m.edited_at,
+        m.deleted_at,
+        (SELECT COUNT(*) FROM message_status WHERE message_id = m.id AND received_at IS NULL) = 0 AS fully_received,
+        (SELECT COUNT(*) FROM message_status WHERE message_id = m.id AND read_at IS NULL) = 0 AS fully_read,
+        s.received_at,
+        s.read_at,
+        m.reply_to,
+        r.id,
+        r.sender,
+        substr(r.content, 1, 101),
+        r.is_photo,
+        r.deleted_at IS NOT NULL,
+        m.kind,
+        m.payload,
+        m.forwarded_from,
+        m.forwarded_from_sender,
+        m.forward_count`
+
+// messageTables joins each message `m` with its conversation `c`, the status `s` of its recipient if it is a 1:1
+// message, and the message `r` it replies to, if any.
+// The status is joined rather than selected with a subquery so that its times keep their TIMESTAMP type.
+const messageTables = `
+    messages m
+    JOIN conversations c ON c.id = m.conversation_id
+    LEFT JOIN message_status s ON s.message_id = m.id AND c.groupname IS NULL
+    LEFT JOIN messages r ON r.id = m.reply_to`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanConversationDetail reads a message selected with messageColumns. Reactions are not loaded.
+func scanConversationDetail(row rowScanner) (ConversationDetail, error) {
+	var msg ConversationDetail
+	var editedAt, deletedAt, receivedAt, readAt sql.NullTime
+	var replyTo, replyID sql.NullInt64
+	var replySender, replyContent sql.NullString
+	var replyIsPhoto, replyDeleted sql.NullBool
+	var payload sql.NullString
+	var forwardedFrom, forwardCount sql.NullInt64
+	var forwardedFromSender sql.NullString
+
+	err := row.Scan(&msg.MessageID, &msg.Content, &msg.Sender, &msg.IsPhoto, &msg.IsForwarded, &msg.Timestamp,
+		&editedAt, &deletedAt, &msg.FullyReceived, &msg.FullyRead, &receivedAt, &readAt,
+		&replyTo, &replyID, &replySender, &replyContent, &replyIsPhoto, &replyDeleted,
+		&msg.Kind, &payload, &forwardedFrom, &forwardedFromSender, &forwardCount)
+	if err != nil {
+		return msg, err
+	}
+
+	if editedAt.Valid {
+		msg.EditedAt = &editedAt.Time
+	}
+	if deletedAt.Valid {
+		msg.DeletedAt = &deletedAt.Time
+	}
+	msg.ReceivedAt = statusTime(receivedAt)
+	msg.ReadAt = statusTime(readAt)
+	if replyTo.Valid {
+		msg.ReplyTo = &ReplyPreview{
+			MessageID: int(replyTo.Int64),
+			Sender:    replySender.String,
+			Snippet:   snippet(replyContent.String, replySnippetLength),
+			IsPhoto:   replyIsPhoto.Bool,
+			Deleted:   !replyID.Valid || replyDeleted.Bool,
+		}
+	}
+	if forwardedFrom.Valid {
+		msg.ForwardedFrom = &ForwardInfo{
+			MessageID:    int(forwardedFrom.Int64),
+			Sender:       forwardedFromSender.String,
+			ForwardCount: int(forwardCount.Int64),
+		}
+	}
+	if payload.Valid {
+		msg.System = &SystemEvent{}
+		if err := json.Unmarshal([]byte(payload.String), msg.System); err != nil {
+			return msg, fmt.Errorf("invalid payload of message %d: %w", msg.MessageID, err)
+		}
+	}
+	return msg, nil
+}
+
+// snippet shortens text to at most n characters, marking the cut with an ellipsis.
+func snippet(text string, n int) string {
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:n]) + "…"
+}
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationFiles contains the schema migrations, embedded in the executable. Each file is named
+// `<version>_<description>.sql`, where version is a positive integer: migrations are applied in version order, and
+// a version must never be changed or reused once released.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var (
+	// ErrSchemaTooNew is returned when the database has been migrated by a newer version of the executable.
+	ErrSchemaTooNew = errors.New("database schema is newer than this executable")
+
+	// ErrForeignKeyViolation is returned when a migration leaves rows referencing missing rows.
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+)
+
+// queryRower is implemented by both *sql.Conn and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// migration is a single schema change.
+type migration struct {
+	version int
+	name    string
+	stmts   string
+}
+
+// migrate brings the database schema to the latest version. Each pending migration is applied in its own transaction,
+// together with its record in the `schema_version` table, so that a failed migration leaves the database at the
+// previous version.
+//
+// Foreign keys are disabled while migrating, as some changes can only be done by rebuilding a table (SQLite can't
+// alter constraints), and dropping the old table must not cascade to the rows referencing it. A migration fails instead
+// if it leaves rows referencing missing rows.
+func migrate(db *sql.DB) error {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return err
+	}
+	return applyMigrations(db, migrations)
+}
+
+// applyMigrations applies the migrations (sorted by version) that the database doesn't have yet.
+func applyMigrations(db *sql.DB, migrations []migration) (err error) {
+	latest := migrations[len(migrations)-1].version
+
+	// PRAGMAs are per connection, so all migrations run on the same one
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error acquiring connection: %w", err)
+	}
+	defer conn.Close()
+
+	var foreignKeys bool
+	if err = conn.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&foreignKeys); err != nil {
+		return fmt.Errorf("error reading foreign keys setting: %w", err)
+	}
+	if foreignKeys {
+		if _, err = conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return fmt.Errorf("error disabling foreign keys: %w", err)
+		}
+		defer func() {
+			if _, fkErr := conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`); fkErr != nil && err == nil {
+				err = fmt.Errorf("error enabling foreign keys: %w", fkErr)
+			}
+		}()
+	}
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER NOT NULL PRIMARY KEY,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("error creating schema_version table: %w", err)
+	}
+
+	current, err := schemaVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, latest known version is %d", ErrSchemaTooNew, current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// schemaVersion returns the version of the last migration applied, 0 if none.
+func schemaVersion(ctx context.Context, q queryRower) (int, error) {
+	var version int
+	err := q.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("error reading schema version: %w", err)
+	}
+	return version, nil
+}
+
+// applyMigration runs a single migration inside a transaction.
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
+
+	// Another process may have applied it in the meantime
+	current, err := schemaVersion(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if current >= m.version {
+		return tx.Commit()
+	}
+
+	// Foreign keys are not enforced while migrating: the migration must not leave references to missing rows. Rows
+	// that were already dangling before it are not its fault, and don't block the upgrade.
+	before, err := foreignKeyViolations(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(m.stmts); err != nil {
+		return fmt.Errorf("error applying migration %d (%s): %w", m.version, m.name, err)
+	}
+	after, err := foreignKeyViolations(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for ref, n := range after {
+		if n > before[ref] {
+			return fmt.Errorf("%w: migration %d (%s) leaves %d rows of %s referencing missing rows of %s",
+				ErrForeignKeyViolation, m.version, m.name, n-before[ref], ref.table, ref.parent)
+		}
+	}
+
+	if _, err = tx.Exec(`INSERT INTO schema_version (version) VALUES (?)`, m.version); err != nil {
+		return fmt.Errorf("error recording migration %d: %w", m.version, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+	return nil
+}
+
+// foreignKey is a reference from a table to another.
+type foreignKey struct {
+	table  string
+	parent string
+}
+
+// foreignKeyViolations counts the rows referencing missing rows, for each table and referenced table.
+func foreignKeyViolations(ctx context.Context, tx *sql.Tx) (map[foreignKey]int, error) {
+	rows, err := tx.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, fmt.Errorf("error checking foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	violations := make(map[foreignKey]int)
+	for rows.Next() {
+		var ref foreignKey
+		var rowid sql.NullInt64
+		var fkid int
+		if err := rows.Scan(&ref.table, &rowid, &ref.parent, &fkid); err != nil {
+			return nil, fmt.Errorf("error reading foreign key violation: %w", err)
+		}
+		violations[ref]++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error checking foreign keys: %w", err)
+	}
+	return violations, nil
+}
+
+// loadMigrations reads the migrations of the `migrations` directory of `fsys`, sorted by version.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, found := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !found || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %q and %q have the same version", other, name)
+		}
+		seen[version] = name
+
+		stmts, err := fs.ReadFile(fsys, path.Join("migrations", name))
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %q: %w", name, err)
+		}
+		migrations = append(migrations, migration{version: version, name: name, stmts: string(stmts)})
+	}
+
+	if len(migrations) == 0 {
+		return nil, errors.New("no migrations found")
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
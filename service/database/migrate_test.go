@@ -0,0 +1,252 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+// baselineSchema is the schema created by the executables released before versioned migrations.
+const baselineSchema = `
+	CREATE TABLE users (
+		username TEXT UNIQUE NOT NULL PRIMARY KEY,
+		profile_photo_url TEXT,
+		auth_token INTEGER UNIQUE NOT NULL
+	);
+	CREATE TABLE conversations (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		user1 TEXT REFERENCES users(username) ON DELETE CASCADE,
+		user2 TEXT REFERENCES users(username) ON DELETE CASCADE,
+		groupname TEXT REFERENCES groups(groupname) ON DELETE CASCADE
+	);
+	CREATE TABLE messages (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		content TEXT,
+		sender TEXT REFERENCES users(username) ON DELETE CASCADE,
+		is_photo BOOLEAN DEFAULT FALSE,
+		is_forwarded BOOLEAN DEFAULT FALSE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE
+	);
+	CREATE TABLE message_status (
+		message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+		user_id TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		received BOOLEAN DEFAULT FALSE,
+		read BOOLEAN DEFAULT FALSE,
+		PRIMARY KEY (message_id, user_id)
+	);
+	CREATE TABLE comments (
+		id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+		reactor_username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+		message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+		content TEXT,
+		UNIQUE (reactor_username, message_id)
+	);
+	CREATE TABLE groups (
+		groupname TEXT NOT NULL PRIMARY KEY,
+		group_photo_url TEXT
+	);
+	CREATE TABLE group_members (
+		groupname TEXT NOT NULL REFERENCES groups(groupname) ON DELETE CASCADE,
+		membername TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE
+	);`
+
+// latestVersion returns the version of the last embedded migration.
+func latestVersion(t *testing.T) int {
+	t.Helper()
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrations[len(migrations)-1].version
+}
+
+func TestMigrateEmptyDatabase(t *testing.T) {
+	conn := newTestConn(t)
+	if err := migrate(conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	var version int
+	if err := conn.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if want := latestVersion(t); version != want {
+		t.Errorf("schema version %d, want %d", version, want)
+	}
+
+	// Migrating again changes nothing
+	if err := migrate(conn); err != nil {
+		t.Fatalf("migrate again: %v", err)
+	}
+	var applied int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM schema_version`).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != version {
+		t.Errorf("%d migrations recorded, want %d", applied, version)
+	}
+}
+
+func TestMigrateBaselineSchema(t *testing.T) {
+	conn := newTestConn(t)
+	exec(t, conn, baselineSchema)
+	exec(t, conn, `INSERT INTO users (username, profile_photo_url, auth_token) VALUES ('alice', '', 1), ('bob', '', 2)`)
+	exec(t, conn, `INSERT INTO groups (groupname, group_photo_url) VALUES ('friends', '')`)
+	exec(t, conn, `INSERT INTO group_members (groupname, membername) VALUES ('friends', 'bob'), ('friends', 'alice')`)
+	exec(t, conn, `INSERT INTO conversations (id, user1, user2) VALUES (1, 'alice', 'bob')`)
+	exec(t, conn, `INSERT INTO messages (id, content, sender, conversation_id) VALUES (1, 'hello', 'alice', 1)`)
+	exec(t, conn, `INSERT INTO message_status (message_id, user_id, received, read) VALUES (1, 'bob', TRUE, FALSE)`)
+	exec(t, conn, `INSERT INTO comments (reactor_username, message_id, content) VALUES ('bob', 1, '👍'), ('alice', 1, '')`)
+
+	if err := migrate(conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	var users int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&users); err != nil || users != 2 {
+		t.Errorf("users: %d, %v, want 2", users, err)
+	}
+
+	// The oldest member of a group becomes its owner
+	var owner string
+	err := conn.QueryRow(`SELECT membername FROM group_members WHERE role = 'owner'`).Scan(&owner)
+	if err != nil || owner != "bob" {
+		t.Errorf("group owner: %q, %v, want bob", owner, err)
+	}
+
+	// Received statuses get the sentinel time, unread ones none
+	var receivedAt string
+	var readAt *string
+	err = conn.QueryRow(`SELECT received_at, read_at FROM message_status WHERE message_id = 1 AND user_id = 'bob'`).
+		Scan(&receivedAt, &readAt)
+	if err != nil || receivedAt == "" || readAt != nil {
+		t.Errorf("status: received %q, read %v, %v, want a received time only", receivedAt, readAt, err)
+	}
+
+	// Empty reactions are dropped
+	var reactions []string
+	rows, err := conn.Query(`SELECT content FROM comments ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			t.Fatal(err)
+		}
+		reactions = append(reactions, content)
+	}
+	_ = rows.Close()
+	if len(reactions) != 1 || reactions[0] != "👍" {
+		t.Errorf("reactions %q, want only 👍", reactions)
+	}
+
+	// The migrated database works
+	db, err := New(conn)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	messageID := 1
+	message, err := db.GetMessage(&messageID, "bob")
+	if err != nil || message.Content != "hello" {
+		t.Errorf("GetMessage: %+v, %v", message, err)
+	}
+}
+
+func TestMigrateSchemaTooNew(t *testing.T) {
+	conn := newTestConn(t)
+	if err := migrate(conn); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, conn, `INSERT INTO schema_version (version) VALUES (?)`, latestVersion(t)+1)
+
+	if err := migrate(conn); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("migrate: got %v, want ErrSchemaTooNew", err)
+	}
+	if _, err := New(conn); !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("New: got %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations(fstest.MapFS{
+		"migrations/0010_later.sql":  {Data: []byte("SELECT 10;")},
+		"migrations/0002_second.sql": {Data: []byte("SELECT 2;")},
+		"migrations/0001_first.sql":  {Data: []byte("SELECT 1;")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []int
+	for _, m := range migrations {
+		versions = append(versions, m.version)
+	}
+	if len(versions) != 3 || versions[0] != 1 || versions[1] != 2 || versions[2] != 10 {
+		t.Errorf("versions %v, want [1 2 10]", versions)
+	}
+}
+
+func TestLoadMigrationsRefusesInvalidFiles(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"duplicate versions": {
+			"migrations/0001_first.sql": {Data: []byte("SELECT 1;")},
+			"migrations/1_again.sql":    {Data: []byte("SELECT 1;")},
+		},
+		"no version":       {"migrations/first.sql": {Data: []byte("SELECT 1;")}},
+		"invalid version":  {"migrations/x1_first.sql": {Data: []byte("SELECT 1;")}},
+		"version zero":     {"migrations/0000_first.sql": {Data: []byte("SELECT 1;")}},
+		"no migrations":    {"migrations/README": {Data: []byte("")}},
+		"no migration dir": {},
+	}
+	for name, fsys := range tests {
+		if _, err := loadMigrations(fsys); err == nil {
+			t.Errorf("%s: loadMigrations succeeded", name)
+		}
+	}
+}
+
+func TestMigrationLeavingDanglingReferencesFails(t *testing.T) {
+	parents := migration{version: 1, name: "0001_parents.sql", stmts: `
+		CREATE TABLE parents (id INTEGER PRIMARY KEY);
+		CREATE TABLE children (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parents(id));`}
+	dangling := migration{version: 2, name: "0002_dangling.sql", stmts: `
+		INSERT INTO children (id, parent_id) VALUES (1, 42);`}
+
+	conn := newTestConn(t)
+	err := applyMigrations(conn, []migration{parents, dangling})
+	if !errors.Is(err, ErrForeignKeyViolation) {
+		t.Fatalf("applyMigrations: got %v, want ErrForeignKeyViolation", err)
+	}
+
+	// The failed migration is rolled back
+	var version, children int
+	if err := conn.QueryRow(`SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil || version != 1 {
+		t.Errorf("schema version %d, %v, want 1", version, err)
+	}
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM children`).Scan(&children); err != nil || children != 0 {
+		t.Errorf("%d children, %v, want 0", children, err)
+	}
+}
+
+func TestMigrationKeepsExistingDanglingReferences(t *testing.T) {
+	parents := migration{version: 1, name: "0001_parents.sql", stmts: `
+		CREATE TABLE parents (id INTEGER PRIMARY KEY);
+		CREATE TABLE children (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parents(id));`}
+	rebuild := migration{version: 2, name: "0002_rebuild.sql", stmts: `
+		CREATE TABLE children_new (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES parents(id), name TEXT);
+		INSERT INTO children_new (id, parent_id) SELECT id, parent_id FROM children;
+		DROP TABLE children;
+		ALTER TABLE children_new RENAME TO children;`}
+
+	conn := newTestConn(t)
+	if err := applyMigrations(conn, []migration{parents}); err != nil {
+		t.Fatal(err)
+	}
+	// A row left dangling while foreign keys were not enforced, before the migration
+	exec(t, conn, `INSERT INTO children (id, parent_id) VALUES (1, 42)`)
+
+	if err := applyMigrations(conn, []migration{parents, rebuild}); err != nil {
+		t.Errorf("applyMigrations: %v", err)
+	}
+}
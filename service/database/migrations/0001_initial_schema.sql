@@ -0,0 +1,51 @@
+-- Initial schema. Tables are created only if missing, so that databases created before the introduction of
+-- versioned migrations are adopted as they are.
+
+CREATE TABLE IF NOT EXISTS users (
+	username TEXT UNIQUE NOT NULL PRIMARY KEY,
+	profile_photo_url TEXT,
+	auth_token INTEGER UNIQUE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS groups (
+	groupname TEXT NOT NULL PRIMARY KEY,
+	group_photo_url TEXT
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+	groupname TEXT NOT NULL REFERENCES groups(groupname) ON DELETE CASCADE,
+	membername TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS conversations (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	user1 TEXT REFERENCES users(username) ON DELETE CASCADE,
+	user2 TEXT REFERENCES users(username) ON DELETE CASCADE,
+	groupname TEXT REFERENCES groups(groupname) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	content TEXT,
+	sender TEXT REFERENCES users(username) ON DELETE CASCADE,
+	is_photo BOOLEAN DEFAULT FALSE,
+	is_forwarded BOOLEAN DEFAULT FALSE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS message_status (
+	message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+	received BOOLEAN DEFAULT FALSE,
+	read BOOLEAN DEFAULT FALSE,
+	PRIMARY KEY (message_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS comments (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	reactor_username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+	message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+	content TEXT,
+	UNIQUE (reactor_username, message_id)
+);
@@ -0,0 +1,5 @@
+-- Keyset pagination of conversations
+CREATE INDEX IF NOT EXISTS messages_conversation_created ON messages (conversation_id, created_at, id);
+
+-- Reactions of a page of messages
+CREATE INDEX IF NOT EXISTS comments_message ON comments (message_id);
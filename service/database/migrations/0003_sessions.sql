@@ -0,0 +1,24 @@
+-- Sessions replace the integer auth token stored on the user. Tokens are only stored hashed.
+CREATE TABLE sessions (
+	token_hash TEXT NOT NULL PRIMARY KEY,
+	username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX sessions_username ON sessions (username);
+
+-- The old tokens were guessable and handed out to anyone knowing the username, so they are dropped: users have to
+-- log in again. SQLite can't drop a UNIQUE column, so the table is rebuilt.
+CREATE TABLE users_new (
+	username TEXT UNIQUE NOT NULL PRIMARY KEY,
+	profile_photo_url TEXT
+);
+
+INSERT INTO users_new (username, profile_photo_url)
+SELECT username, profile_photo_url FROM users;
+
+DROP TABLE users;
+
+ALTER TABLE users_new RENAME TO users;
@@ -0,0 +1,2 @@
+-- Optional password of the user, stored as an encoded KDF hash. NULL until the user sets one.
+ALTER TABLE users ADD COLUMN password_hash TEXT;
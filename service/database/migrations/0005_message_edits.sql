@@ -0,0 +1,12 @@
+-- Time of the last edit of a message, NULL if never edited
+ALTER TABLE messages ADD COLUMN edited_at TIMESTAMP;
+
+-- Previous versions of edited messages: each row is the content a message had until `edited_at`
+CREATE TABLE message_edits (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+	content TEXT,
+	edited_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX message_edits_message ON message_edits (message_id, id);
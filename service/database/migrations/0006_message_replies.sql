@@ -0,0 +1,4 @@
+-- Message a message replies to, NULL if it is not a reply.
+-- No foreign key on purpose: the reply keeps pointing to the original after it is deleted, so it can be shown as a
+-- deleted message.
+ALTER TABLE messages ADD COLUMN reply_to INTEGER;
@@ -0,0 +1,9 @@
+-- Role of each group member: the owner and the admins can moderate the group
+ALTER TABLE group_members ADD COLUMN role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'admin', 'member'));
+
+-- Groups created before roles existed get their longest-standing member as owner
+UPDATE group_members
+SET role = 'owner'
+WHERE rowid IN (SELECT MIN(rowid) FROM group_members GROUP BY groupname);
+
+CREATE INDEX group_members_group ON group_members (groupname, membername);
@@ -0,0 +1,5 @@
+-- Kind of message: 'user' for messages written by users, 'system' for events of a group conversation (e.g. a member
+-- removed). System messages have no sender, their event is stored as JSON in `payload` and `content` holds a readable
+-- description of it.
+ALTER TABLE messages ADD COLUMN kind TEXT NOT NULL DEFAULT 'user' CHECK (kind IN ('user', 'system'));
+ALTER TABLE messages ADD COLUMN payload TEXT;
@@ -0,0 +1,4 @@
+-- When each recipient received and read a message. NULL while the message is not received/read, and for statuses
+-- recorded before the timestamps were introduced.
+ALTER TABLE message_status ADD COLUMN received_at TIMESTAMP;
+ALTER TABLE message_status ADD COLUMN read_at TIMESTAMP;
@@ -0,0 +1,21 @@
+-- The delivery and read status of a message is given by its time alone: NULL while not received/read.
+-- Statuses recorded as received/read before times were tracked get the sentinel time 1970-01-01 00:00:00, read as
+-- "received/read at an unknown time". Reading a message implies having received it.
+CREATE TABLE message_status_new (
+	message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+	received_at TIMESTAMP,
+	read_at TIMESTAMP,
+	PRIMARY KEY (message_id, user_id)
+);
+
+INSERT INTO message_status_new (message_id, user_id, received_at, read_at)
+SELECT
+	message_id,
+	user_id,
+	CASE WHEN received OR read THEN COALESCE(received_at, read_at, '1970-01-01 00:00:00') END,
+	CASE WHEN read THEN COALESCE(read_at, '1970-01-01 00:00:00') END
+FROM message_status;
+
+DROP TABLE message_status;
+ALTER TABLE message_status_new RENAME TO message_status;
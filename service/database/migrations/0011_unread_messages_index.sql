@@ -0,0 +1,2 @@
+-- Unread messages of a user, counted per conversation in the conversation previews
+CREATE INDEX IF NOT EXISTS message_status_user_unread ON message_status (user_id, read_at);
@@ -0,0 +1,5 @@
+-- Conversations of a user, to which message search is restricted. The full-text index itself needs the FTS5 extension
+-- of SQLite, which the executable may be built without, so it is set up at startup instead (see setupSearchIndex).
+CREATE INDEX IF NOT EXISTS conversations_user1 ON conversations (user1);
+CREATE INDEX IF NOT EXISTS conversations_user2 ON conversations (user2);
+CREATE INDEX IF NOT EXISTS group_members_member ON group_members (membername);
@@ -0,0 +1,86 @@
+-- Uploaded files. `name` is the storage key of the file: the SHA-256 of its content, followed by the file extension,
+-- so that uploading the same content twice stores it once. `owner` is the user who first uploaded it.
+--
+-- `refs` counts the photo messages, profile pictures and group pictures using the file, and is kept up to date by the
+-- triggers below, which find the file from the `/uploads/<name>` path of the URL. Files uploaded before this table
+-- existed are not tracked.
+CREATE TABLE media (
+	name TEXT NOT NULL PRIMARY KEY,
+	owner TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+	size INTEGER NOT NULL,
+	mime_type TEXT NOT NULL,
+	refs INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER media_refs_message_insert AFTER INSERT ON messages
+WHEN new.is_photo AND instr(new.content, '/uploads/') > 0
+BEGIN
+	UPDATE media SET refs = refs + 1 WHERE name = substr(new.content, instr(new.content, '/uploads/') + 9);
+END;
+
+CREATE TRIGGER media_refs_message_delete AFTER DELETE ON messages
+WHEN old.is_photo AND instr(old.content, '/uploads/') > 0
+BEGIN
+	UPDATE media SET refs = refs - 1 WHERE name = substr(old.content, instr(old.content, '/uploads/') + 9);
+END;
+
+CREATE TRIGGER media_refs_message_update AFTER UPDATE OF content, is_photo ON messages
+BEGIN
+	UPDATE media SET refs = refs - 1
+	WHERE old.is_photo AND instr(old.content, '/uploads/') > 0
+	AND name = substr(old.content, instr(old.content, '/uploads/') + 9);
+	UPDATE media SET refs = refs + 1
+	WHERE new.is_photo AND instr(new.content, '/uploads/') > 0
+	AND name = substr(new.content, instr(new.content, '/uploads/') + 9);
+END;
+
+CREATE TRIGGER media_refs_user_insert AFTER INSERT ON users
+WHEN instr(new.profile_photo_url, '/uploads/') > 0
+BEGIN
+	UPDATE media SET refs = refs + 1
+	WHERE name = substr(new.profile_photo_url, instr(new.profile_photo_url, '/uploads/') + 9);
+END;
+
+CREATE TRIGGER media_refs_user_update AFTER UPDATE OF profile_photo_url ON users
+WHEN old.profile_photo_url IS NOT new.profile_photo_url
+BEGIN
+	UPDATE media SET refs = refs - 1
+	WHERE instr(old.profile_photo_url, '/uploads/') > 0
+	AND name = substr(old.profile_photo_url, instr(old.profile_photo_url, '/uploads/') + 9);
+	UPDATE media SET refs = refs + 1
+	WHERE instr(new.profile_photo_url, '/uploads/') > 0
+	AND name = substr(new.profile_photo_url, instr(new.profile_photo_url, '/uploads/') + 9);
+END;
+
+CREATE TRIGGER media_refs_user_delete AFTER DELETE ON users
+WHEN instr(old.profile_photo_url, '/uploads/') > 0
+BEGIN
+	UPDATE media SET refs = refs - 1
+	WHERE name = substr(old.profile_photo_url, instr(old.profile_photo_url, '/uploads/') + 9);
+END;
+
+CREATE TRIGGER media_refs_group_insert AFTER INSERT ON groups
+WHEN instr(new.group_photo_url, '/uploads/') > 0
+BEGIN
+	UPDATE media SET refs = refs + 1
+	WHERE name = substr(new.group_photo_url, instr(new.group_photo_url, '/uploads/') + 9);
+END;
+
+CREATE TRIGGER media_refs_group_update AFTER UPDATE OF group_photo_url ON groups
+WHEN old.group_photo_url IS NOT new.group_photo_url
+BEGIN
+	UPDATE media SET refs = refs - 1
+	WHERE instr(old.group_photo_url, '/uploads/') > 0
+	AND name = substr(old.group_photo_url, instr(old.group_photo_url, '/uploads/') + 9);
+	UPDATE media SET refs = refs + 1
+	WHERE instr(new.group_photo_url, '/uploads/') > 0
+	AND name = substr(new.group_photo_url, instr(new.group_photo_url, '/uploads/') + 9);
+END;
+
+CREATE TRIGGER media_refs_group_delete AFTER DELETE ON groups
+WHEN instr(old.group_photo_url, '/uploads/') > 0
+BEGIN
+	UPDATE media SET refs = refs - 1
+	WHERE name = substr(old.group_photo_url, instr(old.group_photo_url, '/uploads/') + 9);
+END;
@@ -0,0 +1,5 @@
+-- Variants generated from an uploaded image (e.g., thumbnails) are files of their own, linked to the original image:
+-- they are used wherever the original is, so they share its references.
+ALTER TABLE media ADD COLUMN variant_of TEXT REFERENCES media(name) ON DELETE CASCADE;
+
+CREATE INDEX media_variant_of ON media (variant_of);
@@ -0,0 +1,15 @@
+-- Time since when an uploaded file is not used anywhere, NULL while it is referenced. Unreferenced files are removed by
+-- the media janitor once they stayed unreferenced for a grace period.
+ALTER TABLE media ADD COLUMN unreferenced_at TIMESTAMP;
+
+-- Files already unreferenced get a full grace period from now
+UPDATE media SET unreferenced_at = CURRENT_TIMESTAMP WHERE refs <= 0;
+
+CREATE TRIGGER media_unreferenced AFTER UPDATE OF refs ON media
+WHEN (old.refs > 0) != (new.refs > 0)
+BEGIN
+	UPDATE media SET unreferenced_at = CASE WHEN new.refs > 0 THEN NULL ELSE CURRENT_TIMESTAMP END
+	WHERE name = new.name;
+END;
+
+CREATE INDEX media_unreferenced_at ON media (unreferenced_at);
@@ -0,0 +1,6 @@
+-- Provenance of forwarded messages: the message first sent, before any forwarding, its sender, and how many times it
+-- was forwarded to reach this copy. NULL for messages that are not forwarded, and for those forwarded before the
+-- provenance was recorded.
+ALTER TABLE messages ADD COLUMN forwarded_from INTEGER;
+ALTER TABLE messages ADD COLUMN forwarded_from_sender TEXT;
+ALTER TABLE messages ADD COLUMN forward_count INTEGER;
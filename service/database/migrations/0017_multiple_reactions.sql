@@ -0,0 +1,20 @@
+-- A user can react to a message with several emojis, each of them once. SQLite can't alter constraints, so the table
+-- is rebuilt. Reactions without content are dropped.
+CREATE TABLE comments_new (
+	id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+	reactor_username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+	message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+	content TEXT NOT NULL,
+	UNIQUE (reactor_username, message_id, content)
+);
+
+INSERT INTO comments_new (id, reactor_username, message_id, content)
+SELECT id, reactor_username, message_id, content
+FROM comments
+WHERE content IS NOT NULL AND content != '';
+
+DROP TABLE comments;
+ALTER TABLE comments_new RENAME TO comments;
+
+-- Reactions of a page of messages
+CREATE INDEX comments_message ON comments (message_id);
@@ -0,0 +1,11 @@
+-- Messages deleted for everyone are kept as tombstones: their content is cleared and deleted_at records when they
+-- were deleted. NULL for messages that are not deleted.
+ALTER TABLE messages ADD COLUMN deleted_at TIMESTAMP;
+
+-- Messages deleted for a single user, which are hidden from their conversations and previews.
+CREATE TABLE hidden_messages (
+	username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+	message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+	hidden_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (username, message_id)
+);
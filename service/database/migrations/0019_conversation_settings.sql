@@ -0,0 +1,21 @@
+-- Settings of a conversation for one of its participants. A conversation without a row has the default settings: not
+-- archived, not muted and not pinned.
+--
+-- `muted_until` is when the conversation stops being muted, NULL if it is not muted. `pin_order` is the position of a
+-- pinned conversation among the pinned ones of the user, lowest first, NULL if it is not pinned.
+CREATE TABLE conversation_settings (
+	username TEXT NOT NULL REFERENCES users(username) ON DELETE CASCADE,
+	conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+	archived BOOLEAN NOT NULL DEFAULT FALSE,
+	muted_until TIMESTAMP,
+	pin_order INTEGER,
+	PRIMARY KEY (username, conversation_id)
+);
+
+-- A new message unarchives the conversation for its recipients
+CREATE TRIGGER conversation_settings_unarchive AFTER INSERT ON messages
+WHEN new.kind = 'user'
+BEGIN
+	UPDATE conversation_settings SET archived = FALSE
+	WHERE conversation_id = new.conversation_id AND username != new.sender AND archived;
+END;
@@ -0,0 +1,3 @@
+-- Time since when the media janitor is removing an uploaded file from the storage, NULL otherwise. The record is only
+-- deleted once the file is gone, so that an upload of the same file meanwhile knows that it must store it again.
+ALTER TABLE media ADD COLUMN deleting_at TIMESTAMP;
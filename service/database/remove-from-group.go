@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrCannotRemoveOwner = errors.New("the group owner can't be removed")
+	ErrRemoveSelf        = errors.New("members can't remove themselves, they leave the group instead")
+)
+
+// RemoveFromGroup removes a member from a group on behalf of a group admin, and records it with a system message in
+// the group conversation.
+// Admins can remove members, only the owner can remove admins, and the owner can't be removed.
+//
+// Parameters:
+// - currentUser: The admin removing the member.
+// - groupname: The name of the group.
+// - member: The member to remove.
+//
+// Returns:
+// - The id of the system message recording the removal.
+// - ErrGroupNotFound, ErrNotGroupMember, ErrNotGroupAdmin, ErrNotGroupOwner, ErrCannotRemoveOwner, ErrRemoveSelf or
+// ErrMemberNotFound if the removal is not allowed, or an error if the database operation fails.
+func (db *appdbimpl) RemoveFromGroup(currentUser string, groupname string, member string) (int, error) {
+	if currentUser == member {
+		return 0, ErrRemoveSelf
+	}
+
+	tx, err := db.c.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	// Defer the rollback in case of any error, to ensure a clean up if something goes wrong
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
+
+	// Step 1: Check the roles of both users
+	currentRole, err := groupRole(tx, groupname, currentUser)
+	if err != nil {
+		return 0, err
+	}
+	memberRole, err := targetRole(tx, groupname, member)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case currentRole == RoleMember:
+		err = ErrNotGroupAdmin
+	case memberRole == RoleOwner:
+		err = ErrCannotRemoveOwner
+	case memberRole == RoleAdmin && currentRole != RoleOwner:
+		err = ErrNotGroupOwner
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Step 2: Remove the member
+	err = deleteGroupMember(tx, groupname, member)
+	if err != nil {
+		return 0, err
+	}
+
+	// Step 3: Record the removal in the group conversation
+	messageID, err := insertGroupSystemMessage(tx, groupname, SystemEvent{
+		Type:   SystemMemberRemoved,
+		Actor:  currentUser,
+		Member: member,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Step 4: Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// deleteGroupMember removes a member from a group. The member's pending delivery and read statuses of the group
+// messages are dropped as well: they would never be updated, and would keep those messages from being fully received
+// and read. The statuses of the messages the member has already read are kept.
+func deleteGroupMember(tx *sql.Tx, groupname string, member string) error {
+	_, err := tx.Exec(`
+		DELETE FROM group_members
+		WHERE groupname = ? AND membername = ?`, groupname, member)
+	if err != nil {
+		return fmt.Errorf("failed to remove member from group: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM message_status
+		WHERE user_id = ? AND read_at IS NULL AND message_id IN (
+			SELECT m.id
+			FROM messages m
+			JOIN conversations c ON c.id = m.conversation_id
+			WHERE c.groupname = ?
+		)`, member, groupname)
+	if err != nil {
+		return fmt.Errorf("failed to remove pending message statuses: %w", err)
+	}
+	return nil
+}
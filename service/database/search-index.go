@@ -0,0 +1,107 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// searchIndexTriggers keep the full-text index in sync with the text of user messages. Photo and system messages are
+// not indexed.
+var searchIndexTriggers = []string{`
+	CREATE TRIGGER messages_fts_insert AFTER INSERT ON messages
+	WHEN new.kind = 'user' AND NOT new.is_photo
+	BEGIN
+		INSERT INTO messages_fts (rowid, content) VALUES (new.id, new.content);
+	END`, `
+	CREATE TRIGGER messages_fts_delete AFTER DELETE ON messages
+	WHEN old.kind = 'user' AND NOT old.is_photo
+	BEGIN
+		INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+	END`, `
+	CREATE TRIGGER messages_fts_update AFTER UPDATE OF content ON messages
+	WHEN old.kind = 'user' AND NOT old.is_photo
+	BEGIN
+		INSERT INTO messages_fts (messages_fts, rowid, content) VALUES ('delete', old.id, old.content);
+		INSERT INTO messages_fts (rowid, content) VALUES (new.id, new.content);
+	END`,
+}
+
+// fts5Available reports whether the SQLite driver includes the FTS5 extension, which go-sqlite3 only compiles in with
+// the `sqlite_fts5` build tag.
+func fts5Available(db *sql.DB) (bool, error) {
+	var fts5 bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		return false, fmt.Errorf("error reading SQLite compile options: %w", err)
+	}
+	return fts5, nil
+}
+
+// setupSearchIndex prepares the full-text index of the messages, `messages_fts`. The index has no copy of the text: it
+// is read from `messages` (external content table), and kept in sync by triggers.
+//
+// The index is not part of the migrations, so that the database also works with an executable built without FTS5:
+// the triggers are then dropped, as they couldn't write to the index, and the index is rebuilt from scratch the next
+// time an executable with FTS5 opens the database.
+func setupSearchIndex(db *sql.DB, fts5 bool) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
+
+	if !fts5 {
+		for _, name := range []string{"messages_fts_insert", "messages_fts_delete", "messages_fts_update"} {
+			if _, err = tx.Exec(`DROP TRIGGER IF EXISTS ` + name); err != nil {
+				return fmt.Errorf("error dropping search index trigger: %w", err)
+			}
+		}
+		return tx.Commit()
+	}
+
+	// The index is up to date as long as its triggers exist
+	var synced bool
+	err = tx.QueryRow(`SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'trigger' AND name = 'messages_fts_insert'`).Scan(&synced)
+	if err != nil {
+		return fmt.Errorf("error reading search index triggers: %w", err)
+	}
+	if synced {
+		return tx.Commit()
+	}
+
+	_, err = tx.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+			content,
+			content = 'messages',
+			content_rowid = 'id',
+			tokenize = 'unicode61 remove_diacritics 2'
+		)`)
+	if err != nil {
+		return fmt.Errorf("error creating search index: %w", err)
+	}
+	if _, err = tx.Exec(`INSERT INTO messages_fts (messages_fts) VALUES ('delete-all')`); err != nil {
+		return fmt.Errorf("error clearing search index: %w", err)
+	}
+	_, err = tx.Exec(`
+		INSERT INTO messages_fts (rowid, content)
+		SELECT id, content FROM messages WHERE kind = 'user' AND NOT is_photo`)
+	if err != nil {
+		return fmt.Errorf("error filling search index: %w", err)
+	}
+	for _, stmt := range searchIndexTriggers {
+		if _, err = tx.Exec(stmt); err != nil {
+			return fmt.Errorf("error creating search index trigger: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit search index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+)
+
+var (
+	// ErrEmptySearch is returned when a search query contains no word to search.
+	ErrEmptySearch = errors.New("search query is empty")
+
+	// ErrSearchUnavailable is returned when the executable was built without FTS5 (see SearchAvailable).
+	ErrSearchUnavailable = errors.New("message search is not available on this server")
+)
+
+// Markers of the matched words in the snippets returned by SQLite. They are control characters, which can't be typed
+// in a message, so that they are told apart from the text once it is escaped.
+const (
+	matchStart = "\x02"
+	matchEnd   = "\x03"
+)
+
+// searchSnippetWords is the maximum number of words of the snippet of a search hit.
+const searchSnippetWords = 12
+
+// SearchMessages finds the text messages matching `query` in the conversations the user participates in, best
+// matches first. Every word of the query must appear in a message, and the last one may be the start of a word.
+//
+// Returns:
+// - The hits from `offset` on, at most `limit` of them, and whether more hits follow.
+// - ErrEmptySearch if the query contains no word, ErrSearchUnavailable without FTS5, or an error if the search fails.
+func (db *appdbimpl) SearchMessages(username string, query string, limit int, offset int) ([]MessageSearchHit, bool, error) {
+	if !db.fts5 {
+		return nil, false, ErrSearchUnavailable
+	}
+
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, false, ErrEmptySearch
+	}
+
+	// One more row than requested is fetched to know whether more hits follow
+	rows, err := db.c.Query(`
+	WITH mine AS (
+		SELECT c.id
+		FROM conversations c
+		LEFT JOIN group_members gm ON gm.groupname = c.groupname AND gm.membername = ?
+		WHERE c.user1 = ? OR c.user2 = ? OR gm.membername IS NOT NULL
+	)
+	SELECT
+		m.id,
+		COALESCE(c.groupname, CASE WHEN c.user1 = ? THEN c.user2 ELSE c.user1 END) AS conversation_name,
+		c.groupname IS NOT NULL AS is_group,
+		COALESCE(m.sender, ''),
+		snippet(messages_fts, 0, ?, ?, '…', ?),
+		m.created_at
+	FROM messages_fts
+	JOIN messages m ON m.id = messages_fts.rowid
+	JOIN conversations c ON c.id = m.conversation_id
+	WHERE messages_fts MATCH ?
+	AND m.conversation_id IN (SELECT id FROM mine)
+	AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.username = ?)
+	ORDER BY messages_fts.rank, m.created_at DESC
+	LIMIT ? OFFSET ?`,
+		username, username, username, username,
+		matchStart, matchEnd, searchSnippetWords,
+		match, username, limit+1, offset)
+	if err != nil {
+		return nil, false, fmt.Errorf("error searching messages: %w", err)
+	}
+	defer rows.Close()
+
+	hits := []MessageSearchHit{}
+	for rows.Next() {
+		var hit MessageSearchHit
+		err := rows.Scan(&hit.MessageID, &hit.ConversationName, &hit.IsGroup, &hit.Sender, &hit.Snippet, &hit.Timestamp)
+		if err != nil {
+			return nil, false, fmt.Errorf("error reading search hit: %w", err)
+		}
+		hit.Snippet = highlight(hit.Snippet)
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("error reading search hits: %w", err)
+	}
+
+	more := len(hits) > limit
+	if more {
+		hits = hits[:limit]
+	}
+	return hits, more, nil
+}
+
+// ftsQuery turns the words typed by a user into an FTS5 query. Each word is quoted, so that it is never read as an
+// operator, and the last one matches as a prefix. It returns "" if there is no word.
+func ftsQuery(query string) string {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return ""
+	}
+	for i, word := range words {
+		words[i] = `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+	}
+	return strings.Join(words, " ") + "*"
+}
+
+// highlight escapes a snippet for HTML, and marks its matched words with <mark> elements.
+func highlight(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, matchStart, "<mark>")
+	return strings.ReplaceAll(snippet, matchEnd, "</mark>")
+}
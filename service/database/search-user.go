@@ -1,33 +1,45 @@
 package database
 
-import "database/sql"
+import "errors"
 
-// Searches for a User in the Database
-// Username can also be only partial
-// RETURNS: all usernames or partialusernames, if found otherwise an error will be returned
-func (db *appdbimpl) SearchUser(partialUsername string) ([]User, error) {
-	var query string
-	var rows *sql.Rows
-	var err error
+// ErrEmptyUserSearch is returned when searching users with an empty query.
+var ErrEmptyUserSearch = errors.New("username query is empty")
 
-	// If partialUsername is empty, retrieve all users from the database
+// SearchUser finds the users whose username contains `partialUsername`, ignoring case. The current user is never
+// returned.
+//
+// Users are ranked by relevance: an exact match first, then the usernames starting with the query, then the ones
+// containing it, shorter usernames first within each group.
+//
+// RETURNS: the matching users from `offset` on, at most `limit` of them, or ErrEmptyUserSearch if the query is empty.
+func (db *appdbimpl) SearchUser(currentUser string, partialUsername string, limit int, offset int) ([]User, error) {
 	if partialUsername == "" {
-		query = "SELECT username, profile_photo_url FROM users"
-		rows, err = db.c.Query(query)
-	} else {
-		// If partialUsername is not empty, use LIKE for searching
-		pattern := partialUsername + "%"
-		query = "SELECT username, profile_photo_url FROM users WHERE username LIKE ?"
-		rows, err = db.c.Query(query, pattern)
+		return nil, ErrEmptyUserSearch
 	}
 
+	// instr is used rather than LIKE, so that '%' and '_' in the query are matched literally
+	rows, err := db.c.Query(`
+		SELECT username, profile_photo_url
+		FROM users
+		WHERE instr(lower(username), lower(?1)) > 0
+		AND username != ?2
+		ORDER BY
+			CASE
+				WHEN lower(username) = lower(?1) THEN 0
+				WHEN instr(lower(username), lower(?1)) = 1 THEN 1
+				ELSE 2
+			END,
+			length(username),
+			username
+		LIMIT ?3 OFFSET ?4`,
+		partialUsername, currentUser, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	// Iterate through all the rows returned from the query
-	var users []User
+	users := []User{}
 	for rows.Next() {
 		var user User
 		if err := rows.Scan(&user.Username, &user.ProfilePhotoURL); err != nil {
@@ -2,10 +2,14 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 )
 
-// SendMessage sends a new message. It allows creating new 1:1 conversations
+// ErrInvalidReply is returned when a message replies to a message that is not in the same conversation.
+var ErrInvalidReply = errors.New("replied message is not part of the conversation")
+
+// SendMessage sends a new message. It allows creating new 1:1 conversations with existing users
 // but only allows sending messages to existing groups.
 func (db *appdbimpl) SendMessage(msg NewMessage) (int, error) {
 	tx, err := db.c.Begin()
@@ -23,7 +27,29 @@ func (db *appdbimpl) SendMessage(msg NewMessage) (int, error) {
 		}
 	}()
 
+	messageID, err := sendMessageTx(tx, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	// Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// sendMessageTx sends a message within the transaction `tx`: see SendMessage.
+//
+// Returns:
+// - The ID of the new message, ErrUserNotFound if the recipient does not exist, an error wrapping ErrNotGroupMember if
+// the sender is not a member of the recipient group, ErrInvalidReply if the replied message is not part of the
+// conversation, or an error if the database operation fails.
+func sendMessageTx(tx *sql.Tx, msg NewMessage) (int, error) {
 	var conversationID int
+	var err error
 
 	// 1. Check if the recipient is a group
 	var isGroup bool
@@ -40,7 +66,7 @@ func (db *appdbimpl) SendMessage(msg NewMessage) (int, error) {
 			return 0, fmt.Errorf("failed to check group membership: %w", err)
 		}
 		if !isMember {
-			return 0, fmt.Errorf("user %s is not a member of group %s", msg.FromUser, msg.ToUser)
+			return 0, fmt.Errorf("user %s can't send messages to group %s: %w", msg.FromUser, msg.ToUser, ErrNotGroupMember)
 		}
 
 		// 3. Check if a conversation for the group already exists
@@ -59,7 +85,17 @@ func (db *appdbimpl) SendMessage(msg NewMessage) (int, error) {
 		}
 
 	} else {
-		// 4. Fetch or create a new 1:1 conversation
+		// 4. The recipient must be an existing user
+		var exists bool
+		err = tx.QueryRow(`SELECT COUNT(*) > 0 FROM users WHERE username = ?`, msg.ToUser).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check if recipient exists: %w", err)
+		}
+		if !exists {
+			return 0, ErrUserNotFound
+		}
+
+		// Fetch or create a new 1:1 conversation
 		err = tx.QueryRow(`
 			SELECT id FROM conversations 
 			WHERE (user1 = ? AND user2 = ?) OR (user2 = ? AND user1 = ?)`,
@@ -77,36 +113,52 @@ func (db *appdbimpl) SendMessage(msg NewMessage) (int, error) {
 		}
 	}
 
-	// 5. Insert new message
+	// 5. A reply must refer to a message of the same conversation
+	var replyTo sql.NullInt64
+	if msg.ReplyTo != 0 {
+		var replyConversationID int
+		err = tx.QueryRow(`SELECT conversation_id FROM messages WHERE id = ?`, msg.ReplyTo).Scan(&replyConversationID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && replyConversationID != conversationID) {
+			return 0, ErrInvalidReply
+		} else if err != nil {
+			return 0, fmt.Errorf("failed to fetch replied message: %w", err)
+		}
+		replyTo = sql.NullInt64{Int64: int64(msg.ReplyTo), Valid: true}
+	}
+
+	// 6. Insert new message
+	var forwardedFrom, forwardCount sql.NullInt64
+	var forwardedFromSender sql.NullString
+	if msg.Forward != nil {
+		forwardedFrom = sql.NullInt64{Int64: int64(msg.Forward.MessageID), Valid: true}
+		forwardedFromSender = sql.NullString{String: msg.Forward.Sender, Valid: true}
+		forwardCount = sql.NullInt64{Int64: int64(msg.Forward.ForwardCount), Valid: true}
+	}
 	var messageID int
 	err = tx.QueryRow(`
-		INSERT INTO messages (content, sender, is_photo, is_forwarded, created_at, conversation_id) 
-		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, ?) RETURNING id`,
-		msg.Content, msg.FromUser, msg.IsPhoto, msg.IsForwarded, conversationID).Scan(&messageID)
+		INSERT INTO messages (content, sender, is_photo, is_forwarded, created_at, conversation_id, reply_to,
+			forwarded_from, forwarded_from_sender, forward_count)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, ?, ?, ?, ?, ?) RETURNING id`,
+		msg.Content, msg.FromUser, msg.IsPhoto, msg.IsForwarded || msg.Forward != nil, conversationID, replyTo,
+		forwardedFrom, forwardedFromSender, forwardCount).Scan(&messageID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert new message: %w", err)
 	}
 
-	// 6. Mark message as "unread" and "unreceived" for the recipients
+	// 7. Mark message as "unread" and "unreceived" for the recipients
 	if isGroup {
 		_, err = tx.Exec(`
-			INSERT INTO message_status (message_id, user_id, received, read) 
-			SELECT ?, membername, FALSE, FALSE FROM group_members WHERE groupname = ? AND membername != ?`,
+			INSERT INTO message_status (message_id, user_id) 
+			SELECT ?, membername FROM group_members WHERE groupname = ? AND membername != ?`,
 			messageID, msg.ToUser, msg.FromUser)
 	} else {
 		_, err = tx.Exec(`
-			INSERT INTO message_status (message_id, user_id, received, read) 
-			VALUES (?, ?, FALSE, FALSE)`, messageID, msg.ToUser)
+			INSERT INTO message_status (message_id, user_id) 
+			VALUES (?, ?)`, messageID, msg.ToUser)
 	}
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert message status: %w", err)
 	}
 
-	// 7. Commit the transaction
-	err = tx.Commit()
-	if err != nil {
-		return 0, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	return messageID, nil
 }
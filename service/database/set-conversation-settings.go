@@ -0,0 +1,45 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrConversationNotFound is returned when the users have not exchanged any message yet.
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// SetConversationSettings replaces the settings of the conversation of `username` with `partnerName` (a user or a
+// group) for `username`. The other participants are not affected.
+//
+// Returns:
+// - ErrConversationNotFound if there is no conversation yet, or an error if the database operation fails.
+func (db *appdbimpl) SetConversationSettings(username, partnerName string, settings ConversationSettings) error {
+	conversationID, err := db.findConversationID(username, partnerName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrConversationNotFound
+	} else if err != nil {
+		return fmt.Errorf("error finding conversation '%s': %w", partnerName, err)
+	}
+
+	var mutedUntil sql.NullTime
+	if settings.MutedUntil != nil {
+		mutedUntil = sql.NullTime{Time: settings.MutedUntil.UTC(), Valid: true}
+	}
+	var pinOrder sql.NullInt64
+	if settings.PinOrder != nil {
+		pinOrder = sql.NullInt64{Int64: int64(*settings.PinOrder), Valid: true}
+	}
+
+	_, err = db.c.Exec(`
+		INSERT INTO conversation_settings (username, conversation_id, archived, muted_until, pin_order)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (username, conversation_id)
+		DO UPDATE SET archived = excluded.archived, muted_until = excluded.muted_until, pin_order = excluded.pin_order`,
+		username, conversationID, settings.Archived, mutedUntil, pinOrder)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation settings: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidRole = errors.New("role must be one of owner, admin or member")
+
+// SetGroupRole changes the role of a group member. Only the owner can change roles.
+// Giving the owner role to another member transfers the ownership: the previous owner becomes an admin.
+// The change is recorded with a system message in the group conversation.
+//
+// Parameters:
+// - currentUser: The user changing the role, must be the owner of the group.
+// - groupname: The name of the group.
+// - member: The member whose role is changed.
+// - role: The new role (RoleOwner, RoleAdmin or RoleMember).
+//
+// Returns:
+// - ErrInvalidRole, ErrGroupNotFound, ErrNotGroupMember, ErrNotGroupOwner or ErrMemberNotFound if the change is not allowed, or an error
+// if the database operation fails.
+func (db *appdbimpl) SetGroupRole(currentUser string, groupname string, member string, role string) error {
+	if role != RoleOwner && role != RoleAdmin && role != RoleMember {
+		return ErrInvalidRole
+	}
+
+	tx, err := db.c.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	// Defer the rollback in case of any error, to ensure a clean up if something goes wrong
+	defer func() {
+		if err != nil {
+			rollbackErr := tx.Rollback()
+			if rollbackErr != nil {
+				err = fmt.Errorf("failed to rollback transaction: %w, original error: %w", rollbackErr, err)
+			}
+		}
+	}()
+
+	// Step 1: Only the owner can change roles
+	currentRole, err := groupRole(tx, groupname, currentUser)
+	if err != nil {
+		return err
+	}
+	if currentRole != RoleOwner {
+		err = ErrNotGroupOwner
+		return err
+	}
+
+	// Step 2: The member must be part of the group. The owner can't demote themselves, the ownership is transferred
+	// instead.
+	memberRole, err := targetRole(tx, groupname, member)
+	if err != nil {
+		return err
+	}
+	if memberRole == RoleOwner {
+		if role != RoleOwner {
+			err = fmt.Errorf("%w: transfer the ownership to another member instead", ErrInvalidRole)
+			return err
+		}
+		err = tx.Commit()
+		if err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+
+	// Step 3: Transferring the ownership demotes the current owner to admin
+	if role == RoleOwner {
+		_, err = tx.Exec(`
+			UPDATE group_members
+			SET role = ?
+			WHERE groupname = ? AND membername = ?`, RoleAdmin, groupname, currentUser)
+		if err != nil {
+			return fmt.Errorf("failed to demote previous owner: %w", err)
+		}
+	}
+
+	// Step 4: Update the role of the member
+	_, err = tx.Exec(`
+		UPDATE group_members
+		SET role = ?
+		WHERE groupname = ? AND membername = ?`, role, groupname, member)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	// Step 5: Record the change in the group conversation
+	_, err = insertGroupSystemMessage(tx, groupname, SystemEvent{
+		Type:   SystemRoleChanged,
+		Actor:  currentUser,
+		Member: member,
+		Role:   role,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Step 6: Commit the transaction
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
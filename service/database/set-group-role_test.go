@@ -0,0 +1,79 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// newGroupDB returns a database with the group "friends", created by alice, with bob, carol and dave as members.
+func newGroupDB(t *testing.T) *appdbimpl {
+	t.Helper()
+	db := newTestDB(t)
+	createUsers(t, db, "alice", "bob", "carol", "dave")
+	if err := db.AddToGroup("friends", []string{"bob", "carol", "dave"}, "alice"); err != nil {
+		t.Fatalf("AddToGroup: %v", err)
+	}
+	return db
+}
+
+func TestSetGroupRoleTransfersOwnership(t *testing.T) {
+	db := newGroupDB(t)
+
+	if err := db.SetGroupRole("alice", "friends", "bob", RoleOwner); err != nil {
+		t.Fatalf("SetGroupRole: %v", err)
+	}
+	want := map[string]string{"alice": RoleAdmin, "bob": RoleOwner, "carol": RoleMember, "dave": RoleMember}
+	if got := groupRoles(t, db, "friends"); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("roles %v, want %v", got, want)
+	}
+	event := lastSystemEvent(t, db, "carol", "friends")
+	if event.Type != SystemRoleChanged || event.Actor != "alice" || event.Member != "bob" || event.Role != RoleOwner {
+		t.Errorf("system event %+v, want alice making bob owner", event)
+	}
+
+	// The previous owner lost the right to change roles, the new one has it
+	if err := db.SetGroupRole("alice", "friends", "carol", RoleAdmin); !errors.Is(err, ErrNotGroupOwner) {
+		t.Errorf("SetGroupRole by the previous owner: got %v, want ErrNotGroupOwner", err)
+	}
+	if err := db.SetGroupRole("bob", "friends", "carol", RoleAdmin); err != nil {
+		t.Errorf("SetGroupRole by the new owner: %v", err)
+	}
+}
+
+func TestSetGroupRoleRefusals(t *testing.T) {
+	db := newGroupDB(t)
+	if err := db.SetGroupRole("alice", "friends", "bob", RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		user    string
+		member  string
+		role    string
+		wantErr error
+	}{
+		{"unknown role", "alice", "carol", "king", ErrInvalidRole},
+		{"owner demoting themselves", "alice", "alice", RoleAdmin, ErrInvalidRole},
+		{"admin changing a role", "bob", "carol", RoleAdmin, ErrNotGroupOwner},
+		{"member changing a role", "carol", "dave", RoleAdmin, ErrNotGroupOwner},
+		{"outsider", "eve", "carol", RoleAdmin, ErrNotGroupMember},
+		{"target outside the group", "alice", "eve", RoleAdmin, ErrMemberNotFound},
+		{"unknown group", "alice", "carol", RoleAdmin, ErrGroupNotFound},
+	}
+	for _, tt := range tests {
+		group := "friends"
+		if tt.name == "unknown group" {
+			group = "enemies"
+		}
+		if err := db.SetGroupRole(tt.user, group, tt.member, tt.role); !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	want := map[string]string{"alice": RoleOwner, "bob": RoleAdmin, "carol": RoleMember, "dave": RoleMember}
+	if got := groupRoles(t, db, "friends"); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("roles %v, want %v", got, want)
+	}
+}
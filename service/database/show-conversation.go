@@ -1,36 +1,80 @@
 package database
 
-import "fmt"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
 
-// ShowConversation retrieves all messages for a specific conversation along with their metadata.
+var ErrInvalidCursor = errors.New("cursor does not belong to the conversation")
+
+// ShowConversation retrieves a page of messages of a specific conversation along with their metadata.
 // It includes details like message content, sender information, timestamps, delivery/read status,
-// and any reactions associated with each message. The function organizes messages in reverse
-// chronological order, ensuring the newest messages are displayed first.
-func (db *appdbimpl) ShowConversation(username, conversationPartnerName string) ([]ConversationDetail, error) {
-	var conversation []ConversationDetail
+// and any reactions associated with each message. Messages are returned in reverse chronological
+// order, ensuring the newest messages are displayed first.
+//
+// Pages are selected with keyset pagination over (created_at, id): `page.Before` returns the messages
+// older than the given message, `page.After` the messages newer than it, and no cursor returns the
+// newest messages. The returned NextCursor continues in the same direction, and is nil on the last page.
+//
+// Messages deleted for everyone are returned as tombstones, while those the user deleted for themselves are skipped.
+func (db *appdbimpl) ShowConversation(username, conversationPartnerName string, page PageRequest) (*ConversationPage, error) {
+	result := &ConversationPage{Messages: []ConversationDetail{}}
 
-	rows, err := db.c.Query(`
-    SELECT 
-        m.id, 
-        m.content,
-		m.sender,
-        m.is_photo, 
-		m.is_forwarded, 
-        m.created_at,
-        (SELECT COUNT(*) FROM message_status WHERE message_id = m.id AND received = FALSE) = 0 AS fully_received,
-        (SELECT COUNT(*) FROM message_status WHERE message_id = m.id AND read = FALSE) = 0 AS fully_read
-    FROM messages m
-    WHERE m.conversation_id IN (
-        SELECT id
-        FROM conversations
-        WHERE 
-            (user1 = ? AND user2 = ?) 
-            OR (user1 = ? AND user2 = ?) 
-            OR groupname = ?
-    )
-    ORDER BY m.created_at DESC`,
-		username, conversationPartnerName, conversationPartnerName, username, conversationPartnerName)
+	// 1. Find the conversation
+	conversationID, err := db.findConversationID(username, conversationPartnerName)
+	if errors.Is(err, sql.ErrNoRows) {
+		// No conversation yet, so there are no messages
+		return result, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error finding conversation '%s': %w", conversationPartnerName, err)
+	}
+
+	// 2. Check that the cursor is a message of this conversation
+	cursor := page.Before
+	if page.After != 0 {
+		cursor = page.After
+	}
+	if cursor != 0 {
+		var exists bool
+		err = db.c.QueryRow(`SELECT COUNT(*) > 0 FROM messages WHERE id = ? AND conversation_id = ?`, cursor, conversationID).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("error checking cursor: %w", err)
+		}
+		if !exists {
+			return nil, ErrInvalidCursor
+		}
+	}
 
+	// 3. Build the keyset query. One more row than requested is fetched to know whether a next page exists.
+	query := `
+    SELECT ` + messageColumns + `
+    FROM ` + messageTables + `
+    WHERE m.conversation_id = ?
+    AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.username = ?)`
+	args := []interface{}{conversationID, username}
+
+	switch {
+	case page.After != 0:
+		query += `
+    AND (m.created_at, m.id) > (SELECT created_at, id FROM messages WHERE id = ?)
+    ORDER BY m.created_at ASC, m.id ASC`
+		args = append(args, page.After)
+	case page.Before != 0:
+		query += `
+    AND (m.created_at, m.id) < (SELECT created_at, id FROM messages WHERE id = ?)
+    ORDER BY m.created_at DESC, m.id DESC`
+		args = append(args, page.Before)
+	default:
+		query += `
+    ORDER BY m.created_at DESC, m.id DESC`
+	}
+	query += `
+    LIMIT ?`
+	args = append(args, page.Limit+1)
+
+	rows, err := db.c.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error querying messages for conversation '%s': %w", conversationPartnerName, err)
 	}
@@ -38,21 +82,13 @@ func (db *appdbimpl) ShowConversation(username, conversationPartnerName string)
 
 	// Populate the conversation details
 	for rows.Next() {
-		var msg ConversationDetail
-
-		if err := rows.Scan(&msg.MessageID, &msg.Content, &msg.Sender, &msg.IsPhoto, &msg.IsForwarded, &msg.Timestamp, &msg.FullyReceived, &msg.FullyRead); err != nil {
-			return nil, fmt.Errorf("error scanning message row: %w", err)
-		}
-
-		// 2. Retrieve reactions for the current message
-		reactions, err := db.getReactionsForMessage(msg.MessageID)
+		msg, err := scanConversationDetail(rows)
 		if err != nil {
-			return nil, fmt.Errorf("error retrieving reactions for message '%d': %w", msg.MessageID, err)
+			return nil, fmt.Errorf("error scanning message row: %w", err)
 		}
-		msg.Reactions = reactions
 
 		// Add the message to the conversation list
-		conversation = append(conversation, msg)
+		result.Messages = append(result.Messages, msg)
 	}
 
 	// Check for errors while iterating through rows
@@ -60,30 +96,114 @@ func (db *appdbimpl) ShowConversation(username, conversationPartnerName string)
 		return nil, fmt.Errorf("error processing rows: %w", err)
 	}
 
-	return conversation, nil
+	// 4. Cut the extra row and compute the cursor of the next page
+	if len(result.Messages) > page.Limit {
+		result.Messages = result.Messages[:page.Limit]
+		next := result.Messages[page.Limit-1].MessageID
+		result.NextCursor = &next
+	}
+
+	// Pages after a cursor were read oldest first, but are returned newest first like any other page
+	if page.After != 0 {
+		for i, j := 0, len(result.Messages)-1; i < j; i, j = i+1, j-1 {
+			result.Messages[i], result.Messages[j] = result.Messages[j], result.Messages[i]
+		}
+	}
+
+	// 5. Retrieve the reactions of all messages of the page at once
+	ids := make([]int, len(result.Messages))
+	for i, msg := range result.Messages {
+		ids[i] = msg.MessageID
+	}
+	reactions, err := db.getReactionsForMessages(ids, username)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Messages {
+		result.Messages[i].Reactions = reactions[result.Messages[i].MessageID]
+	}
+
+	return result, nil
+}
+
+// Helper function: Find the id of the conversation between `username` and `partnerName` (a user or a group).
+// Returns sql.ErrNoRows if there is no such conversation.
+func (db *appdbimpl) findConversationID(username, partnerName string) (int, error) {
+	var conversationID int
+	err := db.c.QueryRow(`
+        SELECT id
+        FROM conversations
+        WHERE 
+            (user1 = ? AND user2 = ?) 
+            OR (user1 = ? AND user2 = ?) 
+            OR groupname = ?
+        LIMIT 1`,
+		username, partnerName, partnerName, username, partnerName).Scan(&conversationID)
+	return conversationID, err
+}
+
+// Helper function: Retrieve the reactions of a given message, as seen by `viewer`
+func (db *appdbimpl) getReactionsForMessage(messageID int, viewer string) ([]Reaction, error) {
+	reactions, err := db.getReactionsForMessages([]int{messageID}, viewer)
+	if err != nil {
+		return nil, err
+	}
+	return reactions[messageID], nil
 }
 
-// Helper function: Retrieve all reactions for a given message
-func (db *appdbimpl) getReactionsForMessage(messageID int) ([]Reaction, error) {
-	var reactions []Reaction
+// Helper function: Retrieve the reactions of several messages with a single query, grouped by message id.
+// The reactions of a message are aggregated per emoji, in order of first use, and Reacted tells whether `viewer` is
+// one of the reactors.
+func (db *appdbimpl) getReactionsForMessages(messageIDs []int, viewer string) (map[int][]Reaction, error) {
+	reactions := make(map[int][]Reaction, len(messageIDs))
+	if len(messageIDs) == 0 {
+		return reactions, nil
+	}
 
-	// Query to retrieve both the reactor's username and content of the reaction
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(messageIDs)), ",")
+	args := make([]interface{}, len(messageIDs))
+	for i, id := range messageIDs {
+		args[i] = id
+	}
+
+	// Query to retrieve the message, the reactor's username and content of the reaction
 	rows, err := db.c.Query(`
-        SELECT reactor_username, content
+        SELECT message_id, reactor_username, content
         FROM comments
-        WHERE message_id = ?`, messageID)
+        WHERE message_id IN (`+placeholders+`)
+        ORDER BY id`, args...)
 	if err != nil {
-		return nil, fmt.Errorf("error querying reactions for message '%d': %w", messageID, err)
+		return nil, fmt.Errorf("error querying reactions: %w", err)
 	}
 	defer rows.Close()
 
-	// Collect all reactions from the result set
+	// Aggregate the reactions of each message by emoji. The rows are in reaction order, so the first row of an emoji
+	// gives its position.
+	type reactionKey struct {
+		messageID int
+		content   string
+	}
+	positions := make(map[reactionKey]int)
 	for rows.Next() {
-		var reaction Reaction
-		if err := rows.Scan(&reaction.Reactor, &reaction.Content); err != nil {
+		var messageID int
+		var reactor, content string
+		if err := rows.Scan(&messageID, &reactor, &content); err != nil {
 			return nil, fmt.Errorf("error scanning reaction: %w", err)
 		}
-		reactions = append(reactions, reaction)
+
+		key := reactionKey{messageID, content}
+		pos, ok := positions[key]
+		if !ok {
+			pos = len(reactions[messageID])
+			positions[key] = pos
+			reactions[messageID] = append(reactions[messageID], Reaction{Content: content})
+		}
+		reaction := &reactions[messageID][pos]
+		reaction.Count++
+		reaction.Reactors = append(reaction.Reactors, reactor)
+		if reactor == viewer {
+			reaction.Reacted = true
+		}
 	}
 
 	// Check for errors while iterating through rows
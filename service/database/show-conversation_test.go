@@ -0,0 +1,128 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// newPaginationDB returns a database with a conversation between alice and bob of 7 messages, whose ids are their
+// positions, sent at 3 distinct times: 1 and 2 first, then 3, 4 and 5 together, then 6 and 7.
+func newPaginationDB(t *testing.T) *appdbimpl {
+	t.Helper()
+	db := newTestDB(t)
+	createUsers(t, db, "alice", "bob", "carol")
+	for i := 1; i <= 7; i++ {
+		if id := sendMessage(t, db, "alice", "bob", fmt.Sprint("message ", i)); id != i {
+			t.Fatalf("message %d got id %d", i, id)
+		}
+	}
+	exec(t, db.c, `UPDATE messages SET created_at = '2024-01-01 10:00:00' WHERE id IN (1, 2)`)
+	exec(t, db.c, `UPDATE messages SET created_at = '2024-01-01 11:00:00' WHERE id IN (3, 4, 5)`)
+	exec(t, db.c, `UPDATE messages SET created_at = '2024-01-01 12:00:00' WHERE id IN (6, 7)`)
+	return db
+}
+
+func TestShowConversationPages(t *testing.T) {
+	db := newPaginationDB(t)
+
+	tests := []struct {
+		name string
+		page PageRequest
+		want []int
+		next int // 0 if there is no next page
+	}{
+		{"newest", PageRequest{Limit: 3}, []int{7, 6, 5}, 5},
+		{"whole conversation", PageRequest{Limit: 7}, []int{7, 6, 5, 4, 3, 2, 1}, 0},
+		{"larger than the conversation", PageRequest{Limit: 50}, []int{7, 6, 5, 4, 3, 2, 1}, 0},
+		{"before", PageRequest{Before: 5, Limit: 3}, []int{4, 3, 2}, 2},
+		{"before, last page", PageRequest{Before: 2, Limit: 3}, []int{1}, 0},
+		{"before, exactly the rest", PageRequest{Before: 4, Limit: 3}, []int{3, 2, 1}, 0},
+		{"before the oldest", PageRequest{Before: 1, Limit: 3}, []int{}, 0},
+		{"before, same time", PageRequest{Before: 4, Limit: 1}, []int{3}, 3},
+		{"after", PageRequest{After: 2, Limit: 3}, []int{5, 4, 3}, 5},
+		{"after, last page", PageRequest{After: 5, Limit: 3}, []int{7, 6}, 0},
+		{"after, same time", PageRequest{After: 3, Limit: 1}, []int{4}, 4},
+		{"after the newest", PageRequest{After: 7, Limit: 3}, []int{}, 0},
+	}
+	for _, tt := range tests {
+		page, err := db.ShowConversation("bob", "alice", tt.page)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := messageIDs(page.Messages); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: messages %v, want %v", tt.name, got, tt.want)
+		}
+		next := 0
+		if page.NextCursor != nil {
+			next = *page.NextCursor
+		}
+		if next != tt.next {
+			t.Errorf("%s: next cursor %d, want %d", tt.name, next, tt.next)
+		}
+	}
+}
+
+func TestShowConversationFollowsCursors(t *testing.T) {
+	db := newPaginationDB(t)
+
+	// Walking back from the newest messages, and then forward from the oldest, visits every message once
+	var backward []int
+	page := PageRequest{Limit: 2}
+	for {
+		result, err := db.ShowConversation("alice", "bob", page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		backward = append(backward, messageIDs(result.Messages)...)
+		if result.NextCursor == nil {
+			break
+		}
+		page = PageRequest{Before: *result.NextCursor, Limit: 2}
+	}
+	if fmt.Sprint(backward) != "[7 6 5 4 3 2 1]" {
+		t.Errorf("backward walk %v, want [7 6 5 4 3 2 1]", backward)
+	}
+
+	var forward []int
+	page = PageRequest{After: 1, Limit: 2}
+	for {
+		result, err := db.ShowConversation("alice", "bob", page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Each page is newest first: prepend it to keep the walk newest first
+		forward = append(messageIDs(result.Messages), forward...)
+		if result.NextCursor == nil {
+			break
+		}
+		page = PageRequest{After: *result.NextCursor, Limit: 2}
+	}
+	if fmt.Sprint(forward) != "[7 6 5 4 3 2]" {
+		t.Errorf("forward walk %v, want [7 6 5 4 3 2]", forward)
+	}
+}
+
+func TestShowConversationRefusesForeignCursors(t *testing.T) {
+	db := newPaginationDB(t)
+	other := sendMessage(t, db, "carol", "bob", "hi")
+
+	for _, page := range []PageRequest{{Before: other, Limit: 3}, {After: other, Limit: 3}, {Before: 999, Limit: 3}} {
+		if _, err := db.ShowConversation("alice", "bob", page); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%+v: got %v, want ErrInvalidCursor", page, err)
+		}
+	}
+}
+
+func TestShowConversationWithoutMessages(t *testing.T) {
+	db := newPaginationDB(t)
+
+	page, err := db.ShowConversation("alice", "carol", PageRequest{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Messages) != 0 || page.NextCursor != nil {
+		t.Errorf("page %+v, want an empty last page", page)
+	}
+}
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// unknownStatusTime is the sentinel time of the statuses recorded as received/read before times were tracked.
+var unknownStatusTime = time.Unix(0, 0)
+
+// statusTime converts a received/read time to its API form: nil if the message is not received/read yet, or if it
+// was at an unknown time.
+func statusTime(t sql.NullTime) *time.Time {
+	if !t.Valid || t.Time.Equal(unknownStatusTime) {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Kinds of messages.
+const (
+	MessageKindUser   = "user"
+	MessageKindSystem = "system"
+)
+
+// Types of the events recorded by system messages.
+const (
+	SystemGroupCreated  = "group_created"
+	SystemMemberAdded   = "member_added"
+	SystemMemberRemoved = "member_removed"
+	SystemMemberLeft    = "member_left"
+	SystemRoleChanged   = "role_changed"
+	SystemRenamed       = "renamed"
+	SystemPhotoChanged  = "photo_changed"
+)
+
+// SystemEvent is the structured content of a system message.
+type SystemEvent struct {
+	Type        string `json:"type"`                   // Type of event (e.g. SystemMemberRemoved)
+	Actor       string `json:"actor"`                  // User who caused the event
+	Member      string `json:"member,omitempty"`       // Member the event is about, if any
+	Role        string `json:"role,omitempty"`         // New role of the member, for SystemRoleChanged
+	RenamedFrom string `json:"renamed_from,omitempty"` // Previous name of the group, for SystemRenamed
+	RenamedTo   string `json:"renamed_to,omitempty"`   // New name of the group, for SystemRenamed
+}
+
+// text returns a readable description of the event, stored as the content of the message for clients that do not
+// render system messages.
+func (e SystemEvent) text() string {
+	switch e.Type {
+	case SystemGroupCreated:
+		return fmt.Sprintf("%s created the group", e.Actor)
+	case SystemMemberAdded:
+		return fmt.Sprintf("%s added %s", e.Actor, e.Member)
+	case SystemMemberRemoved:
+		return fmt.Sprintf("%s removed %s", e.Actor, e.Member)
+	case SystemMemberLeft:
+		return fmt.Sprintf("%s left", e.Actor)
+	case SystemRoleChanged:
+		switch e.Role {
+		case RoleOwner:
+			return fmt.Sprintf("%s is now the owner", e.Member)
+		case RoleAdmin:
+			return fmt.Sprintf("%s is now an admin", e.Member)
+		}
+		return fmt.Sprintf("%s is no longer an admin", e.Member)
+	case SystemRenamed:
+		return fmt.Sprintf("%s renamed the group from %q to %q", e.Actor, e.RenamedFrom, e.RenamedTo)
+	case SystemPhotoChanged:
+		return fmt.Sprintf("%s changed the group photo", e.Actor)
+	}
+	return e.Type
+}
+
+// insertGroupSystemMessage records an event in the conversation of a group and returns the id of the new message.
+// System messages have no recipients in `message_status`, so they are never unread.
+func insertGroupSystemMessage(tx *sql.Tx, groupname string, ev SystemEvent) (int, error) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode system message: %w", err)
+	}
+
+	var messageID int
+	err = tx.QueryRow(`
+		INSERT INTO messages (content, sender, kind, payload, created_at, conversation_id)
+		SELECT ?, NULL, ?, ?, CURRENT_TIMESTAMP, id
+		FROM conversations
+		WHERE groupname = ?
+		RETURNING id`,
+		ev.text(), MessageKindSystem, string(payload), groupname).Scan(&messageID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert system message: %w", err)
+	}
+	return messageID, nil
+}
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"fmt"
+)
+
+// UnmarkMedia clears the mark set by MarkOrphanedMedia on the record of an uploaded file, whose file is kept: it
+// couldn't be removed from the media storage, or it is uploaded again.
+//
+// Returns:
+// - An error if the update fails.
+func (db *appdbimpl) UnmarkMedia(name string) error {
+	_, err := db.c.Exec(`UPDATE media SET deleting_at = NULL WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("error unmarking media %q: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+/*
+Package events implements the in-memory hub used to push notifications to the clients of a user while they are
+connected (e.g., via the Server-Sent Events stream exposed by the api package).
+
+A client subscribes with the username it authenticated as and receives every Event published for that username until
+it unsubscribes or the Hub is closed. Events are only hints that "something changed": clients are expected to re-fetch
+the affected resource through the regular API.
+*/
+package events
+
+import (
+	"errors"
+	"sync"
+)
+
+// Event types published by the API.
+const (
+	MessageSent    = "message_sent"
+	MessageEdited  = "message_edited"
+	MessageDeleted = "message_deleted"
+	CommentAdded   = "comment_added"
+	CommentDeleted = "comment_deleted"
+	GroupChanged   = "group_changed"
+
+	// MessagesReceived and MessagesRead tell the senders that messages of the conversation have been received or
+	// read by the actor, up to MessageID (or all of them if it is 0).
+	MessagesReceived = "messages_received"
+	MessagesRead     = "messages_read"
+)
+
+// subscriptionBuffer is the number of events that can be queued for a slow subscriber before it is dropped
+const subscriptionBuffer = 32
+
+// ErrHubClosed is returned when subscribing to a Hub that has already been closed.
+var ErrHubClosed = errors.New("event hub closed")
+
+// Event is a notification for a single recipient.
+type Event struct {
+	Type         string `json:"type"`                 // One of the event types above
+	Conversation string `json:"conversation"`         // Name of the conversation as seen by the recipient
+	IsGroup      bool   `json:"is_group"`             // Whether the conversation is a group conversation
+	Actor        string `json:"actor"`                // Username of the user who triggered the event
+	MessageID    int    `json:"message_id,omitempty"` // Affected message (if any)
+}
+
+// Subscription is a stream of events for a single connected client.
+type Subscription struct {
+	// C receives the events for the user. It is closed when the subscription ends (unsubscribed, dropped because the
+	// client was too slow, or hub closed).
+	C <-chan Event
+
+	c        chan Event
+	username string
+	closed   bool
+}
+
+// Hub fans out events to all the subscriptions of a user.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*Subscription]struct{}
+	closed      bool
+	active      sync.WaitGroup
+}
+
+// NewHub returns an empty, ready to use Hub.
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a new subscription for `username`. Callers must call Unsubscribe when done.
+func (h *Hub) Subscribe(username string) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+
+	c := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{C: c, c: c, username: username}
+	if h.subscribers[username] == nil {
+		h.subscribers[username] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[username][sub] = struct{}{}
+	h.active.Add(1)
+	return sub, nil
+}
+
+// Unsubscribe removes the subscription from the hub and closes its channel.
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(sub)
+	h.active.Done()
+}
+
+// Publish sends the event to every subscription of `username`. It never blocks: a subscriber whose buffer is full is
+// dropped, and its client is expected to reconnect and re-fetch its data.
+func (h *Hub) Publish(username string, ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subscribers[username] {
+		select {
+		case sub.c <- ev:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Rename moves the subscriptions of `oldUsername` to `newUsername`, so that connected clients keep receiving events
+// after a username change.
+func (h *Hub) Rename(oldUsername, newUsername string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs, ok := h.subscribers[oldUsername]
+	if !ok {
+		return
+	}
+	delete(h.subscribers, oldUsername)
+	if h.subscribers[newUsername] == nil {
+		h.subscribers[newUsername] = make(map[*Subscription]struct{})
+	}
+	for sub := range subs {
+		sub.username = newUsername
+		h.subscribers[newUsername][sub] = struct{}{}
+	}
+}
+
+// Close ends all subscriptions and waits until every subscriber has unsubscribed. No new subscriptions are accepted
+// afterwards.
+func (h *Hub) Close() error {
+	h.mu.Lock()
+	h.closed = true
+	for _, subs := range h.subscribers {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+	h.mu.Unlock()
+
+	h.active.Wait()
+	return nil
+}
+
+// remove detaches the subscription and closes its channel. The caller must hold h.mu.
+func (h *Hub) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.c)
+
+	delete(h.subscribers[sub.username], sub)
+	if len(h.subscribers[sub.username]) == 0 {
+		delete(h.subscribers, sub.username)
+	}
+}
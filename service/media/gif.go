@@ -0,0 +1,69 @@
+package media
+
+import "encoding/binary"
+
+// gifFrames scans the blocks of a GIF file without decoding them, and returns the number of frames and the total
+// number of pixels of the frames, as given by their image descriptors. It returns ok = false if the structure of the
+// file is invalid.
+//
+// A GIF file is a header, a logical screen descriptor and an optional global color table, followed by blocks: an
+// extension (0x21) is a label and data sub-blocks, a frame (0x2C) is an image descriptor, an optional local color
+// table and the LZW-compressed data in sub-blocks, and the trailer (0x3B) ends the file.
+func gifFrames(data []byte) (frames int, pixels int64, ok bool) {
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return 0, 0, false
+	}
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << ((flags & 0x07) + 1)
+	}
+
+	for pos < len(data) {
+		switch data[pos] {
+		case 0x21:
+			// Extension: introducer, label and sub-blocks
+			if pos = skipSubBlocks(data, pos+2); pos < 0 {
+				return 0, 0, false
+			}
+		case 0x2C:
+			// Frame: separator, left, top, width, height and flags, then the color table and the image data
+			if pos+10 > len(data) {
+				return 0, 0, false
+			}
+			width := int64(binary.LittleEndian.Uint16(data[pos+5:]))
+			height := int64(binary.LittleEndian.Uint16(data[pos+7:]))
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << ((flags & 0x07) + 1)
+			}
+			// The LZW minimum code size comes before the sub-blocks
+			if pos = skipSubBlocks(data, pos+1); pos < 0 {
+				return 0, 0, false
+			}
+			frames++
+			pixels += width * height
+		case 0x3B:
+			return frames, pixels, true
+		default:
+			return 0, 0, false
+		}
+	}
+
+	// The trailer is missing
+	return 0, 0, false
+}
+
+// skipSubBlocks skips the data sub-blocks starting at `pos`, each one a length byte followed by that many bytes, up
+// to the empty one ending them. It returns the position after them, or -1 if they are truncated.
+func skipSubBlocks(data []byte, pos int) int {
+	for pos < len(data) {
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos
+		}
+		pos += size
+	}
+	return -1
+}
@@ -0,0 +1,247 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Limits of the uploaded images. Dimensions, and the frames of GIF images, are checked before decoding, so that a small
+// file can't make the server allocate a huge image.
+const (
+	MaxImageDimension = 8192     // Maximum width and height
+	MaxImagePixels    = 40000000 // Maximum width × height
+
+	// An animated GIF decodes to all of its frames at once: the number of frames and their total pixels are limited
+	// too, as a small file of highly compressible frames can hold hundreds of them.
+	MaxAnimationFrames = 300
+	MaxAnimationPixels = 100000000 // Maximum width × height of the frames, summed
+)
+
+// Sizes of the variants generated for each image.
+const (
+	PreviewSize = 320 // Chat previews fit in a PreviewSize × PreviewSize box
+	AvatarSize  = 128 // Avatars are AvatarSize × AvatarSize squares, cropped at the center
+)
+
+// jpegQuality is the quality of the re-encoded JPEG images.
+const jpegQuality = 90
+
+var (
+	// ErrUnsupportedImage is returned when the content of a file is not a JPEG, PNG or GIF image.
+	ErrUnsupportedImage = errors.New("unsupported image type, allowed types are JPEG, PNG and GIF")
+
+	// ErrInvalidImage is returned when an image can't be decoded.
+	ErrInvalidImage = errors.New("invalid image")
+
+	// ErrImageTooLarge is returned when the dimensions or the frames of an image exceed the limits.
+	ErrImageTooLarge = fmt.Errorf("image is too large, the maximum is %d×%d pixels and %d frames",
+		MaxImageDimension, MaxImageDimension, MaxAnimationFrames)
+)
+
+// File is a file ready to be stored.
+type File struct {
+	Name     string // Content-addressed name (see Name)
+	Data     []byte
+	MimeType string
+}
+
+// ProcessedImage is an uploaded image, re-encoded, with its variants.
+type ProcessedImage struct {
+	Original File // The image, re-encoded without metadata
+	Preview  File // A smaller version for chat previews, named after Original
+	Avatar   File // A square thumbnail for profile and group pictures, named after Original
+}
+
+// ProcessImage validates an uploaded image and prepares it to be stored.
+//
+// The type of the image is detected from its content, regardless of its file name. The image is then decoded and
+// re-encoded, which drops any metadata (e.g., EXIF camera and GPS data). The EXIF orientation of JPEG images is
+// applied to the pixels before it is dropped, so that photos keep their orientation.
+//
+// Returns:
+// - The re-encoded image and its variants.
+// - ErrUnsupportedImage, ErrInvalidImage or ErrImageTooLarge if the image is refused.
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	mimeType := http.DetectContentType(data)
+	if mimeType != "image/jpeg" && mimeType != "image/png" && mimeType != "image/gif" {
+		return nil, ErrUnsupportedImage
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width > MaxImageDimension || cfg.Height > MaxImageDimension || cfg.Width*cfg.Height > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+	if cfg.Width == 0 || cfg.Height == 0 {
+		return nil, ErrInvalidImage
+	}
+
+	// Decode and re-encode the image. The variants are made from its first frame.
+	var encoded bytes.Buffer
+	var img image.Image
+	switch mimeType {
+	case "image/gif":
+		frames, pixels, ok := gifFrames(data)
+		if !ok {
+			return nil, ErrInvalidImage
+		}
+		if frames > MaxAnimationFrames || pixels > MaxAnimationPixels {
+			return nil, ErrImageTooLarge
+		}
+		animation, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil || len(animation.Image) == 0 {
+			return nil, ErrInvalidImage
+		}
+		if err := gif.EncodeAll(&encoded, animation); err != nil {
+			return nil, fmt.Errorf("error encoding image: %w", err)
+		}
+		img = animation.Image[0]
+	case "image/png":
+		img, err = png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		if err := png.Encode(&encoded, img); err != nil {
+			return nil, fmt.Errorf("error encoding image: %w", err)
+		}
+	default:
+		img, err = jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, ErrInvalidImage
+		}
+		img = orient(img, jpegOrientation(data))
+		if err := jpeg.Encode(&encoded, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, fmt.Errorf("error encoding image: %w", err)
+		}
+	}
+
+	result := &ProcessedImage{
+		Original: File{Name: Name(encoded.Bytes(), extensions[mimeType]), Data: encoded.Bytes(), MimeType: mimeType},
+	}
+	base := strings.TrimSuffix(result.Original.Name, extensions[mimeType])
+
+	// Variants of JPEG images are JPEG, the others PNG, which keeps the transparency
+	variantType := "image/png"
+	if mimeType == "image/jpeg" {
+		variantType = "image/jpeg"
+	}
+
+	result.Preview, err = encodeVariant(fit(img, PreviewSize), base+"-preview", variantType)
+	if err != nil {
+		return nil, err
+	}
+	result.Avatar, err = encodeVariant(square(img, AvatarSize), base+"-avatar", variantType)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// extensions maps the supported image types to the extension of their files.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// encodeVariant encodes a variant of an image, named `name` followed by the extension of `mimeType`.
+func encodeVariant(img image.Image, name string, mimeType string) (File, error) {
+	var buf bytes.Buffer
+	var err error
+	if mimeType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("error encoding image variant: %w", err)
+	}
+	return File{Name: name + extensions[mimeType], Data: buf.Bytes(), MimeType: mimeType}, nil
+}
+
+// fit scales an image down to fit in a size × size box, keeping its aspect ratio. Smaller images are not enlarged.
+func fit(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	if w >= h {
+		w, h = size, h*size/w
+	} else {
+		w, h = w*size/h, size
+	}
+	if w == 0 {
+		w = 1
+	}
+	if h == 0 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+// square crops the largest centered square of an image, and scales it to size × size.
+func square(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, image.Rect(x0, y0, x0+side, y0+side), draw.Src, nil)
+	return dst
+}
+
+// orient applies an EXIF orientation (1 to 8) to an image, so that it displays correctly without it.
+func orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		// Orientations 5 to 8 swap width and height
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				sx, sy = w-1-x, y
+			case 3: // Rotated 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // Mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // Transposed
+				sx, sy = y, x
+			case 6: // Rotated 90° clockwise to display
+				sx, sy = y, h-1-x
+			case 7: // Transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // Rotated 90° counterclockwise to display
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,98 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// testGIF encodes an animated GIF of `frames` frames of width × height pixels.
+func testGIF(t *testing.T, frames int, width int, height int) []byte {
+	t.Helper()
+
+	palette := color.Palette{color.Black, color.White}
+	animation := &gif.GIF{}
+	for i := 0; i < frames; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, width, height), palette)
+		frame.SetColorIndex(i%width, 0, 1)
+		animation.Image = append(animation.Image, frame)
+		animation.Delay = append(animation.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, animation); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// craftedGIF returns a GIF whose screen and frames claim to be width × height pixels, with a single byte of image
+// data each: it is tiny, but would decode to huge frames.
+func craftedGIF(frames int, width uint16, height uint16) []byte {
+	var b bytes.Buffer
+	b.WriteString("GIF89a")
+	_ = binary.Write(&b, binary.LittleEndian, [2]uint16{width, height})
+	b.Write([]byte{0x80, 0, 0})             // Global color table of 2 colors
+	b.Write([]byte{0, 0, 0, 255, 255, 255}) // The color table
+	for i := 0; i < frames; i++ {
+		b.WriteByte(0x2C)
+		_ = binary.Write(&b, binary.LittleEndian, [4]uint16{0, 0, width, height})
+		b.WriteByte(0)                 // No local color table
+		b.Write([]byte{2, 1, 0x44, 0}) // LZW code size, a sub-block of 1 byte, the end of the sub-blocks
+	}
+	b.WriteByte(0x3B)
+	return b.Bytes()
+}
+
+func TestGIFFrames(t *testing.T) {
+	frames, pixels, ok := gifFrames(testGIF(t, 3, 4, 5))
+	if !ok || frames != 3 || pixels != 3*4*5 {
+		t.Errorf("gifFrames = %d, %d, %v, want 3, 60, true", frames, pixels, ok)
+	}
+
+	frames, pixels, ok = gifFrames(craftedGIF(2, 8000, 8000))
+	if !ok || frames != 2 || pixels != 2*8000*8000 {
+		t.Errorf("gifFrames of crafted GIF = %d, %d, %v, want 2, 128000000, true", frames, pixels, ok)
+	}
+
+	data := testGIF(t, 2, 4, 4)
+	for _, invalid := range [][]byte{nil, []byte("GIF89a"), data[:len(data)-1], data[:len(data)/2]} {
+		if _, _, ok := gifFrames(invalid); ok {
+			t.Errorf("gifFrames accepted invalid data %x", invalid)
+		}
+	}
+}
+
+func TestProcessImageGIF(t *testing.T) {
+	processed, err := ProcessImage(testGIF(t, 3, 40, 30))
+	if err != nil {
+		t.Fatalf("ProcessImage: %v", err)
+	}
+	animation, err := gif.DecodeAll(bytes.NewReader(processed.Original.Data))
+	if err != nil || len(animation.Image) != 3 {
+		t.Fatalf("re-encoded GIF: %v, want 3 frames", err)
+	}
+	if processed.Original.MimeType != "image/gif" || processed.Preview.MimeType != "image/png" {
+		t.Errorf("types %s and %s, want image/gif and image/png", processed.Original.MimeType, processed.Preview.MimeType)
+	}
+}
+
+func TestProcessImageRefusesLargeAnimations(t *testing.T) {
+	tests := map[string][]byte{
+		"too many frames":  testGIF(t, MaxAnimationFrames+1, 1, 1),
+		"too many pixels":  craftedGIF(2, 8000, 8000),
+		"too large screen": craftedGIF(1, 9000, 10),
+	}
+	for name, data := range tests {
+		if _, err := ProcessImage(data); !errors.Is(err, ErrImageTooLarge) {
+			t.Errorf("%s: got %v, want ErrImageTooLarge", name, err)
+		}
+	}
+
+	if _, err := ProcessImage(craftedGIF(1, 0, 0)[:30]); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("truncated GIF: got %v, want ErrInvalidImage", err)
+	}
+}
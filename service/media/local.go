@@ -0,0 +1,80 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage stores files in a directory of the local filesystem.
+type LocalStorage struct {
+	dir string
+}
+
+// NewLocalStorage returns a LocalStorage keeping the files in `dir`, which is created if missing.
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating media directory: %w", err)
+	}
+	return &LocalStorage{dir: dir}, nil
+}
+
+// Put writes the file to a temporary file first, and then moves it in place, so that a file is never read while it
+// is partially written.
+func (s *LocalStorage) Put(_ context.Context, name string, data []byte, _ string) (err error) {
+	if !ValidName(name) {
+		return ErrInvalidName
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("error setting file permissions: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("error moving file in place: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalStorage) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	if !ValidName(name) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	return f, nil
+}
+
+func (s *LocalStorage) Delete(_ context.Context, name string) error {
+	if !ValidName(name) {
+		return ErrInvalidName
+	}
+	err := os.Remove(filepath.Join(s.dir, name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("error deleting file: %w", err)
+	}
+	return nil
+}
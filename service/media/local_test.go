@@ -0,0 +1,76 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewLocalStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := Name([]byte("content"), ".txt")
+	if err := s.Put(ctx, name, []byte("content"), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	// Putting the same file again replaces it
+	if err := s.Put(ctx, name, []byte("content"), "text/plain"); err != nil {
+		t.Fatalf("Put again: %v", err)
+	}
+
+	f, err := s.Open(ctx, name)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, err := io.ReadAll(f)
+	_ = f.Close()
+	if err != nil || string(data) != "content" {
+		t.Fatalf("Open read %q, %v, want %q", data, err, "content")
+	}
+
+	// Only the file is left in the directory, without temporary files
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != name {
+		t.Errorf("directory contains %v, want only %s", entries, name)
+	}
+
+	if err := s.Delete(ctx, name); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Open(ctx, name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete: got %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, name); err != nil {
+		t.Errorf("Delete of a missing file: %v", err)
+	}
+}
+
+func TestLocalStorageInvalidNames(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "../escape.txt", ".hidden", "dir/file.txt"} {
+		if err := s.Put(ctx, name, []byte("x"), "text/plain"); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Put(%q): got %v, want ErrInvalidName", name, err)
+		}
+		if _, err := s.Open(ctx, name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Open(%q): got %v, want ErrNotFound", name, err)
+		}
+		if err := s.Delete(ctx, name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("Delete(%q): got %v, want ErrInvalidName", name, err)
+		}
+	}
+}
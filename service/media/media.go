@@ -0,0 +1,59 @@
+/*
+Package media stores the files uploaded by the users (e.g., photos sent in chats and profile pictures).
+
+Files are content-addressed: a file is stored under a name made of the SHA-256 hash of its content and its extension
+(see Name), so that the same content is always stored once, and a stored file never changes.
+
+Where files are stored is abstracted by the Storage interface. LocalStorage keeps them in a directory of the local
+filesystem, S3Storage in a bucket of an S3-compatible object storage (e.g., AWS S3, or MinIO for local development).
+*/
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned when a file is not in the storage.
+var ErrNotFound = errors.New("file not found")
+
+// ErrInvalidName is returned when a name can't be the name of a stored file.
+var ErrInvalidName = errors.New("invalid file name")
+
+// Storage stores files by name.
+type Storage interface {
+	// Put stores `data` with the given name and MIME type, replacing any file with the same name.
+	Put(ctx context.Context, name string, data []byte, mimeType string) error
+
+	// Open returns the content of a file, or ErrNotFound. The caller must close it.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+
+	// Delete removes a file. Deleting a file that does not exist is not an error.
+	Delete(ctx context.Context, name string) error
+}
+
+// Name returns the content-addressed name of a file: the hex SHA-256 hash of `data`, followed by `ext` (e.g. ".png").
+func Name(data []byte, ext string) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]) + strings.ToLower(ext)
+}
+
+// ValidName reports whether `name` can be the name of a stored file: it must be a single path element made of
+// letters, digits, '-', '_' and '.', and can't start with a dot.
+func ValidName(name string) bool {
+	if name == "" || name[0] == '.' {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package media
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	// SHA-256 of "hello"
+	const hash = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	if got := Name([]byte("hello"), ".PNG"); got != hash+".png" {
+		t.Errorf("Name = %q, want %q", got, hash+".png")
+	}
+	if !ValidName(Name([]byte("hello"), ".jpg")) {
+		t.Error("Name returned an invalid name")
+	}
+}
+
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"0123456789abcdef.png", true},
+		{"0123456789abcdef-preview.jpg", true},
+		{"Some_File.v2.gif", true},
+		{"", false},
+		{".upload-123", false},
+		{"..", false},
+		{"../etc/passwd", false},
+		{"dir/file.png", false},
+		{`dir\file.png`, false},
+		{"file name.png", false},
+		{"file.png?x=1", false},
+		{"fìle.png", false},
+		{strings.Repeat("a", 64) + "\x00", false},
+	}
+	for _, tt := range tests {
+		if got := ValidName(tt.name); got != tt.want {
+			t.Errorf("ValidName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
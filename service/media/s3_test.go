@@ -0,0 +1,258 @@
+package media
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Credentials of the fake S3 service
+const (
+	testRegion    = "eu-south-1"
+	testBucket    = "media"
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// fakeS3 is a minimal S3 service keeping the objects of a single bucket in memory. It checks the AWS Signature
+// Version 4 of every request with its own implementation, and answers 403 to the requests with a wrong signature.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg := verifySignature(r, body); msg != "" {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>"+msg+"</Message></Error>", http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/"+testBucket+"/")
+	if key == r.URL.Path || key == "" {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = fakeObject{data: body, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		object, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		_, _ = w.Write(object.data)
+	case http.MethodDelete:
+		// Like S3, deleting a missing object succeeds
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySignature checks the signature of a request signed with the host and x-amz-* headers, and returns what is
+// wrong with it, or "" if it is valid.
+func verifySignature(r *http.Request, body []byte) string {
+	payloadHash := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(payloadHash[:]) {
+		return "payload hash mismatch"
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return "invalid X-Amz-Date"
+	}
+	if d := time.Since(signedAt); d > 15*time.Minute || d < -15*time.Minute {
+		return "request time too skewed"
+	}
+
+	scope := amzDate[:8] + "/" + testRegion + "/s3/aws4_request"
+	prefix := "AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/" + scope +
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, prefix) {
+		return "unexpected credential or signed headers: " + auth
+	}
+
+	canonicalRequest := r.Method + "\n" +
+		r.URL.EscapedPath() + "\n" +
+		r.URL.RawQuery + "\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-content-sha256:" + r.Header.Get("X-Amz-Content-Sha256") + "\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		r.Header.Get("X-Amz-Content-Sha256")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + testSecretKey)
+	for _, part := range []string{amzDate[:8], testRegion, "s3", "aws4_request"} {
+		mac := hmac.New(sha256.New, key)
+		_, _ = mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(stringToSign))
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(strings.TrimPrefix(auth, prefix)), []byte(want)) {
+		return "signature mismatch"
+	}
+	return ""
+}
+
+// newTestS3 starts a fake S3 service and returns it with a storage for its bucket, using the given secret key.
+func newTestS3(t *testing.T, secretKey string) (*fakeS3, *S3Storage) {
+	t.Helper()
+
+	fake := &fakeS3{objects: make(map[string]fakeObject)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	s, err := NewS3Storage(S3Config{
+		Endpoint:  server.URL + "/",
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secretKey,
+		Client:    server.Client(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, s
+}
+
+func TestS3Storage(t *testing.T) {
+	ctx := context.Background()
+	fake, s := newTestS3(t, testSecretKey)
+
+	name := Name([]byte("image data"), ".png")
+	if err := s.Put(ctx, name, []byte("image data"), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if object := fake.objects[name]; string(object.data) != "image data" || object.contentType != "image/png" {
+		t.Fatalf("stored object %q (%s), want %q (image/png)", object.data, object.contentType, "image data")
+	}
+
+	f, err := s.Open(ctx, name)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, err := io.ReadAll(f)
+	_ = f.Close()
+	if err != nil || string(data) != "image data" {
+		t.Fatalf("Open read %q, %v, want %q", data, err, "image data")
+	}
+
+	if err := s.Delete(ctx, name); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := fake.objects[name]; ok {
+		t.Fatal("object still stored after Delete")
+	}
+	if _, err := s.Open(ctx, name); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete: got %v, want ErrNotFound", err)
+	}
+	// S3 answers 204 when deleting a missing object
+	if err := s.Delete(ctx, name); err != nil {
+		t.Errorf("Delete of a missing object: %v", err)
+	}
+}
+
+func TestS3StorageEmptyFile(t *testing.T) {
+	ctx := context.Background()
+	_, s := newTestS3(t, testSecretKey)
+
+	if err := s.Put(ctx, "empty.txt", nil, "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	f, err := s.Open(ctx, "empty.txt")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	_ = f.Close()
+}
+
+func TestS3StorageWrongCredentials(t *testing.T) {
+	ctx := context.Background()
+	_, s := newTestS3(t, "wrong secret")
+
+	err := s.Put(ctx, "file.png", []byte("data"), "image/png")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put with a wrong secret: got %v, want a 403 error", err)
+	}
+	if _, err := s.Open(ctx, "file.png"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Open with a wrong secret: got %v, want a 403 error", err)
+	}
+	if err := s.Delete(ctx, "file.png"); err == nil {
+		t.Error("Delete with a wrong secret succeeded")
+	}
+}
+
+func TestS3StorageInvalidNames(t *testing.T) {
+	ctx := context.Background()
+	fake, s := newTestS3(t, testSecretKey)
+
+	if err := s.Put(ctx, "../other-bucket/file.png", []byte("x"), "image/png"); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Put: got %v, want ErrInvalidName", err)
+	}
+	if _, err := s.Open(ctx, "../other-bucket/file.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open: got %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, "../other-bucket/file.png"); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("Delete: got %v, want ErrInvalidName", err)
+	}
+	if len(fake.objects) != 0 {
+		t.Errorf("objects stored: %v", fake.objects)
+	}
+}
+
+func TestNewS3StorageValidatesConfig(t *testing.T) {
+	valid := S3Config{Endpoint: "http://localhost:9000", Region: testRegion, Bucket: testBucket,
+		AccessKey: testAccessKey, SecretKey: testSecretKey}
+	if _, err := NewS3Storage(valid); err != nil {
+		t.Fatalf("valid config: %v", err)
+	}
+
+	for name, change := range map[string]func(*S3Config){
+		"ftp endpoint":     func(c *S3Config) { c.Endpoint = "ftp://localhost" },
+		"no host":          func(c *S3Config) { c.Endpoint = "http://" },
+		"no region":        func(c *S3Config) { c.Region = "" },
+		"no bucket":        func(c *S3Config) { c.Bucket = "" },
+		"no access key":    func(c *S3Config) { c.AccessKey = "" },
+		"no secret key":    func(c *S3Config) { c.SecretKey = "" },
+		"invalid endpoint": func(c *S3Config) { c.Endpoint = "http://[::1" },
+	} {
+		cfg := valid
+		change(&cfg)
+		if _, err := NewS3Storage(cfg); err == nil {
+			t.Errorf("%s: NewS3Storage succeeded", name)
+		}
+	}
+}